@@ -12,22 +12,149 @@ var (
 	)
 	usernameField = field.StringField(
 		"username",
-		field.WithRequired(true),
-		field.WithDescription("JD Edwards EnterpriseOne username."),
+		field.WithDescription("JD Edwards EnterpriseOne username. Required unless the oauth auth mode is used."),
 	)
 	passwordField = field.StringField(
 		"password",
-		field.WithRequired(true),
-		field.WithDescription("JD Edwards EnterpriseOne password."),
+		field.WithDescription("JD Edwards EnterpriseOne password. Required unless the oauth auth mode is used."),
 	)
 	envField = field.StringField(
 		"env",
 		field.WithDescription("Environment to use for login. If not specified, the default environment configured for the AIS Server will be used."),
 	)
+	roleField = field.StringField(
+		"role",
+		field.WithDescription("Role to use for login, e.g. *ALL or a specific role. If not specified, the default role configured for the AIS Server will be used."),
+	)
+	deviceNameField = field.StringField(
+		"device-name",
+		field.WithDescription("Device name sent to the AIS Server when requesting a token."),
+		field.WithDefaultValue("baton-jd-edwards"),
+	)
+	authModeField = field.StringField(
+		"auth-mode",
+		field.WithDescription("How requests to the AIS Server are authenticated: token (an AIS session is opened through tokenrequest), "+
+			"basic (HTTP Basic credentials on every request) or oauth (OAuth 2.0 client credentials access token on every request)."),
+		field.WithDefaultValue("token"),
+	)
+	oauthTokenURLField = field.StringField(
+		"oauth-token-url",
+		field.WithDescription("OAuth 2.0 token endpoint of the identity provider protecting the AIS Server."),
+	)
+	oauthClientIDField = field.StringField(
+		"oauth-client-id",
+		field.WithDescription("OAuth 2.0 client ID used with the oauth auth mode."),
+	)
+	oauthClientSecretField = field.StringField(
+		"oauth-client-secret",
+		field.WithDescription("OAuth 2.0 client secret used with the oauth auth mode."),
+	)
+	oauthScopesField = field.StringSliceField(
+		"oauth-scopes",
+		field.WithDescription("OAuth 2.0 scopes requested with the oauth auth mode."),
+	)
+	tlsCABundleField = field.StringField(
+		"tls-ca-bundle",
+		field.WithDescription("Path of a PEM file with the certificate authorities to trust for the AIS Server, in addition to the system ones."),
+	)
+	tlsClientCertField = field.StringField(
+		"tls-client-cert",
+		field.WithDescription("Path of the PEM encoded client certificate presented to the AIS Server for mutual TLS."),
+	)
+	tlsClientKeyField = field.StringField(
+		"tls-client-key",
+		field.WithDescription("Path of the PEM encoded private key of the client certificate."),
+	)
+	tlsMinVersionField = field.StringField(
+		"tls-min-version",
+		field.WithDescription("Minimum TLS version accepted from the AIS Server: 1.2 or 1.3."),
+		field.WithDefaultValue("1.2"),
+	)
+	proxyURLField = field.StringField(
+		"proxy-url",
+		field.WithDescription("HTTP proxy used to reach the AIS Server. If not specified, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honoured."),
+	)
+	noProxyField = field.StringField(
+		"no-proxy",
+		field.WithDescription("Comma separated hosts reached without the proxy, in the NO_PROXY format. Defaults to NO_PROXY."),
+	)
+	requestTimeoutField = field.IntField(
+		"request-timeout",
+		field.WithDescription("Timeout in seconds of a single request to the AIS Server, including reading its response."),
+		field.WithDefaultValue(300),
+	)
+	maxRetriesField = field.IntField(
+		"max-retries",
+		field.WithDescription("How many times a read request is retried when the AIS Server fails or can't be reached. 0 disables retries."),
+		field.WithDefaultValue(3),
+	)
+	requestsPerSecondField = field.IntField(
+		"requests-per-second",
+		field.WithDescription("Maximum number of requests sent to the AIS Server per second. 0 means no limit."),
+	)
+	maxInFlightField = field.IntField(
+		"max-in-flight",
+		field.WithDescription("Maximum number of concurrent requests to the AIS Server. 0 means no limit."),
+	)
+	paginationModeField = field.StringField(
+		"pagination-mode",
+		field.WithDescription("How tables are paged: links (follow the next page links of AIS), keyset (request each page by "+
+			"key ranges) or auto (keyset on AIS v1, which has no next page links, and links on v2)."),
+		field.WithDefaultValue("auto"),
+	)
+	pageSizeField = field.IntField(
+		"page-size",
+		field.WithDescription("Number of rows requested from the AIS Server at once. Lowered to the maximum page size of the AIS Server, if it reports one."),
+		field.WithDefaultValue(100),
+	)
+	recordLimitField = field.IntField(
+		"record-limit",
+		field.WithDescription("Maximum number of rows read from a single table query, the remaining rows are skipped. 0 means no limit."),
+	)
+	grantSyncModeField = field.StringField(
+		"grant-sync-mode",
+		field.WithDescription("How role members are synced: per-role (query F95921 for every role) or bulk (read F95921 once "+
+			"per sync and spill it to a temporary file when it is large)."),
+		field.WithDefaultValue("per-role"),
+	)
+	effectiveDatesField = field.StringField(
+		"effective-dates",
+		field.WithDescription("What to do with role assignments outside of their effective dates in F95921: flag (sync them, "+
+			"with their status in the grant metadata) or drop (only sync the assignments in effect today)."),
+		field.WithDefaultValue("flag"),
+	)
 	configurationFields = []field.SchemaField{
 		aisUrlField,
 		usernameField,
 		passwordField,
 		envField,
+		roleField,
+		deviceNameField,
+		authModeField,
+		oauthTokenURLField,
+		oauthClientIDField,
+		oauthClientSecretField,
+		oauthScopesField,
+		tlsCABundleField,
+		tlsClientCertField,
+		tlsClientKeyField,
+		tlsMinVersionField,
+		proxyURLField,
+		noProxyField,
+		requestTimeoutField,
+		maxRetriesField,
+		requestsPerSecondField,
+		maxInFlightField,
+		paginationModeField,
+		pageSizeField,
+		recordLimitField,
+		grantSyncModeField,
+		effectiveDatesField,
+	}
+	configurationRelations = []field.SchemaFieldRelationship{
+		field.FieldsRequiredTogether(usernameField, passwordField),
+		field.FieldsRequiredTogether(oauthTokenURLField, oauthClientIDField, oauthClientSecretField),
+		field.FieldsAtLeastOneUsed(usernameField, oauthClientIDField),
+		field.FieldsRequiredTogether(tlsClientCertField, tlsClientKeyField),
 	}
 )
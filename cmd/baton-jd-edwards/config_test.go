@@ -11,7 +11,7 @@ import (
 func TestConfigs(t *testing.T) {
 	test.ExerciseTestCasesFromExpressions(
 		t,
-		field.NewConfiguration(configurationFields),
+		field.NewConfiguration(configurationFields, configurationRelations...),
 		nil,
 		ustrings.ParseFlags,
 		[]test.TestCaseFromExpression{
@@ -30,6 +30,51 @@ func TestConfigs(t *testing.T) {
 				true,
 				"is valid with optional field",
 			},
+			{
+				"--ais-url 1 --username 1 --password 1 --env PD --role *ALL --device-name baton",
+				true,
+				"is valid with session fields",
+			},
+			{
+				"--ais-url 1 --username 1 --password 1 --auth-mode basic",
+				true,
+				"is valid with basic auth mode",
+			},
+			{
+				"--ais-url 1 --auth-mode oauth --oauth-token-url 1 --oauth-client-id 1 --oauth-client-secret 1 --oauth-scopes a,b",
+				true,
+				"is valid with oauth auth mode",
+			},
+			{
+				"--ais-url 1 --username 1 --password 1 --tls-ca-bundle ca.pem --tls-client-cert c.pem --tls-client-key k.pem --tls-min-version 1.3",
+				true,
+				"is valid with tls options",
+			},
+			{
+				"--ais-url 1 --username 1 --password 1 --proxy-url http://proxy:3128 --no-proxy localhost --request-timeout 60 --max-retries 5 --requests-per-second 10 --max-in-flight 4 --pagination-mode keyset --page-size 50 --record-limit 1000 --grant-sync-mode bulk --effective-dates drop",
+				true,
+				"is valid with http options",
+			},
+			{
+				"--ais-url 1 --username 1 --password 1 --tls-client-cert c.pem",
+				false,
+				"client certificate requires key",
+			},
+			{
+				"--ais-url 1 --username 1",
+				false,
+				"username requires password",
+			},
+			{
+				"--ais-url 1 --oauth-client-id 1",
+				false,
+				"oauth client id requires token url and secret",
+			},
+			{
+				"--ais-url 1",
+				false,
+				"requires username or oauth client",
+			},
 		},
 	)
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/conductorone/baton-jd-edwards/pkg/connector"
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
 	configSchema "github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/field"
@@ -26,15 +28,23 @@ func main() {
 		ctx,
 		connectorName,
 		getConnector,
-		field.NewConfiguration(configurationFields),
+		field.NewConfiguration(configurationFields, configurationRelations...),
 	)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 
+	err = relayServiceStdin(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	closeSessionsOnSignal()
+
 	cmd.Version = version
 	err = cmd.Execute()
+	closeSessions()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
@@ -43,16 +53,78 @@ func main() {
 
 func getConnector(ctx context.Context, cfg *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
-	cb, err := connector.New(ctx,
-		cfg.GetString(aisUrlField.FieldName),
-		cfg.GetString(usernameField.FieldName),
-		cfg.GetString(passwordField.FieldName),
-		cfg.GetString(envField.FieldName),
-	)
+	authMode, err := jde.ParseAuthMode(cfg.GetString(authModeField.FieldName))
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+
+	paginationMode, err := jde.ParsePaginationMode(cfg.GetString(paginationModeField.FieldName))
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+
+	grantSyncMode, err := connector.ParseGrantSyncMode(cfg.GetString(grantSyncModeField.FieldName))
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+
+	effectiveDates, err := connector.ParseEffectiveDatesMode(cfg.GetString(effectiveDatesField.FieldName))
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+
+	cb, err := connector.New(ctx, connector.Config{
+		AisUrl: cfg.GetString(aisUrlField.FieldName),
+		Credentials: jde.Credentials{
+			AuthMode:    authMode,
+			Username:    cfg.GetString(usernameField.FieldName),
+			Password:    cfg.GetString(passwordField.FieldName),
+			Environment: cfg.GetString(envField.FieldName),
+			Role:        cfg.GetString(roleField.FieldName),
+			DeviceName:  cfg.GetString(deviceNameField.FieldName),
+			OAuth: jde.OAuthCredentials{
+				TokenURL:     cfg.GetString(oauthTokenURLField.FieldName),
+				ClientID:     cfg.GetString(oauthClientIDField.FieldName),
+				ClientSecret: cfg.GetString(oauthClientSecretField.FieldName),
+				Scopes:       cfg.GetStringSlice(oauthScopesField.FieldName),
+			},
+		},
+		HTTP: jde.HTTPOptions{
+			TLS: jde.TLSOptions{
+				CABundle:   cfg.GetString(tlsCABundleField.FieldName),
+				ClientCert: cfg.GetString(tlsClientCertField.FieldName),
+				ClientKey:  cfg.GetString(tlsClientKeyField.FieldName),
+				MinVersion: cfg.GetString(tlsMinVersionField.FieldName),
+			},
+			ProxyURL:       cfg.GetString(proxyURLField.FieldName),
+			NoProxy:        cfg.GetString(noProxyField.FieldName),
+			RequestTimeout: time.Duration(cfg.GetInt(requestTimeoutField.FieldName)) * time.Second,
+			UserAgent:      connectorName + "/" + version,
+		},
+		Retry: jde.RetryOptions{
+			MaxRetries: cfg.GetInt(maxRetriesField.FieldName),
+		},
+		RateLimit: jde.RateLimitOptions{
+			RequestsPerSecond: cfg.GetInt(requestsPerSecondField.FieldName),
+			MaxInFlight:       cfg.GetInt(maxInFlightField.FieldName),
+		},
+		Pagination: paginationMode,
+		Limits: jde.QueryLimits{
+			PageSize:    cfg.GetInt(pageSizeField.FieldName),
+			RecordLimit: cfg.GetInt(recordLimitField.FieldName),
+		},
+		GrantSync:      grantSyncMode,
+		EffectiveDates: effectiveDates,
+	})
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
+	trackSession(ctx, cb)
 
 	c, err := connectorbuilder.NewConnector(ctx, cb)
 	if err != nil {
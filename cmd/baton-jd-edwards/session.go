@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/connector"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	// serviceCommand is the hidden command the connector runner starts to serve the connector in a subprocess. The
+	// SDK ends that subprocess with os.Exit as soon as its stdin is closed, see MakeGRPCServerCommand, so neither
+	// signals nor the return of cmd.Execute reach it. TestServiceCommand checks the SDK still registers it.
+	serviceCommand = "_connector-service"
+	logoutTimeout  = 30 * time.Second
+)
+
+// sessions holds the connectors created by this process, so their AIS sessions are released before it exits.
+var sessions struct {
+	mtx        sync.Mutex
+	connectors []*connector.Connector
+	loggers    []*zap.Logger
+}
+
+func trackSession(ctx context.Context, cb *connector.Connector) {
+	sessions.mtx.Lock()
+	defer sessions.mtx.Unlock()
+
+	sessions.connectors = append(sessions.connectors, cb)
+	sessions.loggers = append(sessions.loggers, ctxzap.Extract(ctx))
+}
+
+// closeSessions logs out of every AIS session opened by this process.
+func closeSessions() {
+	sessions.mtx.Lock()
+	defer sessions.mtx.Unlock()
+
+	for i, cb := range sessions.connectors {
+		l := sessions.loggers[i]
+		ctx, cancel := context.WithTimeout(ctxzap.ToContext(context.Background(), l), logoutTimeout)
+		err := cb.Close(ctx)
+		cancel()
+		if err != nil {
+			l.Error("error closing AIS session", zap.Error(err))
+		}
+	}
+
+	sessions.connectors = nil
+	sessions.loggers = nil
+}
+
+// relayServiceStdin makes the connector service subprocess release its sessions before it exits: stdin is relayed
+// through a pipe, and the sessions are closed before the end of input is passed on to the SDK.
+func relayServiceStdin(args []string) error {
+	if !slices.Contains(args, serviceCommand) {
+		return nil
+	}
+
+	r, err := relayStdin(os.Stdin, closeSessions)
+	if err != nil {
+		return err
+	}
+	os.Stdin = r
+
+	return nil
+}
+
+// relayStdin returns a pipe carrying the input of stdin, which calls onEOF before closing the pipe.
+func relayStdin(stdin io.Reader, onEOF func()) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		_, _ = io.Copy(w, stdin)
+		onEOF()
+		_ = w.Close()
+	}()
+
+	return r, nil
+}
+
+// closeSessionsOnSignal logs out of the AIS sessions when the process is interrupted or terminated, then raises the
+// signal again so that it takes its usual course: the connector runner stops on an interrupt, anything else ends the
+// process. Otherwise sessions are closed once cmd.Execute returns, or at the end of input of the service subprocess.
+func closeSessionsOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		closeSessions()
+		signal.Stop(signals)
+
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			_ = p.Signal(sig)
+		}
+	}()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	configSchema "github.com/conductorone/baton-sdk/pkg/config"
+	"github.com/conductorone/baton-sdk/pkg/field"
+)
+
+func TestRelayStdin(t *testing.T) {
+	var closed atomic.Bool
+	r, err := relayStdin(strings.NewReader("config\n"), func() {
+		closed.Store(true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "config\n" {
+		t.Errorf("got input %q", b)
+	}
+	// the SDK exits on the end of input, sessions must be closed by then.
+	if !closed.Load() {
+		t.Error("expected sessions to be closed before the end of input")
+	}
+}
+
+func TestServiceCommand(t *testing.T) {
+	_, cmd, err := configSchema.DefineConfiguration(
+		context.Background(),
+		connectorName,
+		getConnector,
+		field.NewConfiguration(configurationFields, configurationRelations...),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == serviceCommand {
+			return
+		}
+	}
+	t.Errorf("the SDK no longer serves the connector through %s, sessions of the subprocess won't be released", serviceCommand)
+}
@@ -2,27 +2,31 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/conductorone/baton-jd-edwards/pkg/jde"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
-	"github.com/conductorone/baton-sdk/pkg/uhttp"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Connector struct {
-	client  *jde.Client
-	version string
-	aisUrl  string
+	client *jde.Client
+	creds  jde.Credentials
+	// roleIndex is only set in GrantSyncBulk.
+	roleIndex      *roleIndex
+	effectiveDates EffectiveDatesMode
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
 		newUserBuilder(d.client),
-		newRoleBuilder(d.client),
+		newRoleBuilder(d.client, d.roleIndex, d.effectiveDates),
+		newGroupBuilder(d.client),
 	}
 }
 
@@ -30,7 +34,7 @@ func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.Reso
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
 		DisplayName: "JD Edwards Connector",
-		Description: "Connector syncing users and roles from JD Edwards EnterpriseOne.",
+		Description: "Connector syncing users, roles and user groups from JD Edwards EnterpriseOne.",
 	}, nil
 }
 
@@ -38,20 +42,14 @@ func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error)
 // to be sure that they are valid.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
 	// check if all capabilities are configured
-	config, capabilitiesMissing, version, err := jde.GetConfig(ctx, d.aisUrl)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching config: %w", err)
-	}
-
-	if capabilitiesMissing {
-		return nil, fmt.Errorf("capabilities missing, make sure dataservice and tokenrequest capabilities are configured on the AIS server")
+	features := d.client.Features()
+	if features.MissingRequired {
+		return nil, status.Error(codes.FailedPrecondition, "capabilities missing, make sure dataservice and tokenrequest capabilities are configured on the AIS server")
 	}
 
-	validateTokenConfigured := jde.CanValidateToken(ctx, config)
-
-	// if we are using v1 or don't have validate token configured, we need to validate token differently.
-	if version == "v1" || !validateTokenConfigured {
-		err = d.client.ValidateTokenV1(ctx)
+	// if we don't have validate token configured or don't use a token at all, we need to validate differently.
+	if !features.ValidateToken || d.creds.AuthMode != jde.AuthModeToken {
+		err := d.client.ValidateTokenV1(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error validating token: %w", err)
 		}
@@ -64,38 +62,71 @@ func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, erro
 	}
 
 	if res.Message != "" {
-		return nil, fmt.Errorf("error validating token: %s", res.Message)
+		return nil, status.Errorf(codes.Unauthenticated, "error validating token: %s", res.Message)
 	}
 
 	return nil, nil
 }
 
+// Close releases the AIS session held by the connector and removes the files it spilled role assignments to.
+// It must be called once the connector is no longer used.
+func (d *Connector) Close(ctx context.Context) error {
+	var indexErr error
+	if d.roleIndex != nil {
+		indexErr = d.roleIndex.Close()
+	}
+	return errors.Join(d.client.Logout(ctx), indexErr)
+}
+
+// Config holds the settings the connector is created with.
+type Config struct {
+	AisUrl      string
+	Credentials jde.Credentials
+	HTTP        jde.HTTPOptions
+	Retry       jde.RetryOptions
+	RateLimit   jde.RateLimitOptions
+	Pagination  jde.PaginationMode
+	Limits      jde.QueryLimits
+	GrantSync   GrantSyncMode
+	// EffectiveDates selects whether role assignments out of their effective dates are synced.
+	EffectiveDates EffectiveDatesMode
+}
+
 // New returns a new instance of the connector.
-func New(ctx context.Context, aisUrl, username, password, env string) (*Connector, error) {
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+func New(ctx context.Context, cfg Config) (*Connector, error) {
+	httpClient, err := jde.NewHTTPClient(ctx, cfg.HTTP)
 	if err != nil {
 		return nil, err
 	}
 
-	// call config to see which AIS version we are using
-	_, _, version, err := jde.GetConfig(ctx, aisUrl)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching config: %w", err)
+	creds := cfg.Credentials
+	if creds.AuthMode == jde.AuthModeOAuth {
+		creds.TokenSource = jde.NewOAuthTokenSource(ctx, httpClient, creds.OAuth)
 	}
 
-	token, err := jde.Authenticate(ctx, aisUrl, username, password, env, version)
+	client, err := jde.NewClient(httpClient, cfg.AisUrl, creds, jde.ClientOptions{
+		Retry:      cfg.Retry,
+		RateLimit:  cfg.RateLimit,
+		Pagination: cfg.Pagination,
+		Limits:     cfg.Limits,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error authenticating: %w", err)
+		return nil, fmt.Errorf("error creating client: %w", err)
 	}
 
-	client, err := jde.NewClient(httpClient, aisUrl, token, env, version)
-	if err != nil {
-		return nil, fmt.Errorf("error creating client: %w", err)
+	// call config once to see which AIS version and capabilities we are using
+	if _, err := client.Negotiate(ctx); err != nil {
+		return nil, err
 	}
 
-	return &Connector{
-		client:  client,
-		version: version,
-		aisUrl:  aisUrl,
-	}, nil
+	d := &Connector{
+		client:         client,
+		creds:          creds,
+		effectiveDates: cfg.EffectiveDates,
+	}
+	if cfg.GrantSync == GrantSyncBulk {
+		d.roleIndex = newRoleIndex(client)
+	}
+
+	return d, nil
 }
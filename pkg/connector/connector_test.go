@@ -0,0 +1,60 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+)
+
+func TestCloseLogsOut(t *testing.T) {
+	var sessions, logouts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/defaultconfig"):
+			fmt.Fprint(w, `{"aisVersion": "9.2", "capabilityList": [{"name": "dataservice"}, {"name": "outputType"}]}`)
+		case strings.HasSuffix(r.URL.Path, "/tokenrequest/logout"):
+			logouts.Add(1)
+			fmt.Fprint(w, `{}`)
+		case strings.HasSuffix(r.URL.Path, "/tokenrequest"):
+			n := sessions.Add(1)
+			fmt.Fprintf(w, `{"userInfo": {"token": "t%d"}}`, n)
+		default:
+			fmt.Fprint(w, `{"fs_DATABROWSE_F0092": {"data": {"gridData": {"rowset": [{"F0092_USER": "A"}]}}}}`)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	d, err := New(ctx, Config{
+		AisUrl:      srv.URL,
+		Credentials: jde.Credentials{AuthMode: jde.AuthModeToken, Username: "u", Password: "p"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.client.Users().Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := logouts.Load(); n != 1 {
+		t.Fatalf("expected the session to be logged out once, got %d logouts", n)
+	}
+
+	// a request still in flight once the connector is closed must not open another session.
+	if _, err := d.client.Users().Next(ctx); err == nil {
+		t.Error("expected requests to fail after Close")
+	}
+	if n := sessions.Load(); n != 1 {
+		t.Errorf("expected a single session, got %d", n)
+	}
+}
@@ -0,0 +1,180 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type groupBuilder struct {
+	resourceType *v2.ResourceType
+	client       *jde.Client
+}
+
+const groupMembership = "member"
+
+func (g *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return g.resourceType
+}
+
+// Create a new connector resource for a JD Edwards user group.
+func groupResource(group string) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"group_id": group,
+	}
+
+	groupTraitOptions := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
+	}
+
+	ret, err := rs.NewGroupResource(
+		group,
+		groupResourceType,
+		group,
+		groupTraitOptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: groupResourceType.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	pager := g.client.Groups().Resume(page)
+	err = pager.NextFunc(ctx, func(group jde.Group) error {
+		gr, err := groupResource(group.ID)
+		if err != nil {
+			return fmt.Errorf("error creating group resource: %w", err)
+		}
+		rv = append(rv, gr)
+		return nil
+	})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error fetching groups: %w", err)
+	}
+
+	nextToken, err := bag.NextToken(pager.Token())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextToken, annotationsForRateLimit(g.client), nil
+}
+
+func (g *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("%s Group %s", resource.DisplayName, groupMembership)),
+		ent.WithDescription(fmt.Sprintf("Member of %s user group in JD Edwards EnterpriseOne", resource.DisplayName)),
+	}
+
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		groupMembership,
+		assignmentOptions...,
+	))
+
+	return rv, "", nil, nil
+}
+
+func (g *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: userResourceType.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	pager := g.client.GroupMembers(resource.Id.Resource).Resume(page)
+	err = pager.NextFunc(ctx, func(user jde.User) error {
+		userID, err := rs.NewResourceID(userResourceType, user.ID)
+		if err != nil {
+			return fmt.Errorf("error creating user resource for group %s: %w", resource.Id.Resource, err)
+		}
+
+		rv = append(rv, grant.NewGrant(
+			resource,
+			groupMembership,
+			userID,
+		))
+		return nil
+	})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error fetching group members: %w", err)
+	}
+
+	nextToken, err := bag.NextToken(pager.Token())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextToken, annotationsForRateLimit(g.client), nil
+}
+
+// Grant moves the user into the group. A JD Edwards user belongs to a single user group, so the user leaves the
+// group it was in.
+func (g *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	if principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("only users can be granted group membership, got %s", principal.Id.ResourceType)
+	}
+
+	userID := principal.Id.Resource
+	groupID := entitlement.Resource.Id.Resource
+	user, found, err := g.client.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	if user.Group == groupID {
+		return nil, nil
+	}
+
+	if err := g.client.SetUserGroup(ctx, userID, groupID); err != nil {
+		return nil, fmt.Errorf("error adding user %s to group %s: %w", userID, groupID, err)
+	}
+
+	return nil, nil
+}
+
+// Revoke removes the user from the group, unless the user was moved to another group since.
+func (g *groupBuilder) Revoke(ctx context.Context, gr *v2.Grant) (annotations.Annotations, error) {
+	userID := gr.Principal.Id.Resource
+	groupID := gr.Entitlement.Resource.Id.Resource
+	user, found, err := g.client.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !found || user.Group != groupID {
+		return nil, nil
+	}
+
+	if err := g.client.SetUserGroup(ctx, userID, ""); err != nil {
+		return nil, fmt.Errorf("error removing user %s from group %s: %w", userID, groupID, err)
+	}
+
+	return nil, nil
+}
+
+func newGroupBuilder(client *jde.Client) *groupBuilder {
+	return &groupBuilder{
+		resourceType: groupResourceType,
+		client:       client,
+	}
+}
@@ -0,0 +1,163 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// userProfilesServer serves the user group of JDOE in F0092, and records the application stack requests sent to
+// P0092 as "action form: command control=value ...". failOn makes the form of the request whose action or form
+// starts with it report an error.
+type userProfilesServer struct {
+	*httptest.Server
+	group  string
+	failOn string
+
+	mtx      sync.Mutex
+	requests []string
+}
+
+func newUserProfilesServer(t *testing.T, group string) *userProfilesServer {
+	s := &userProfilesServer{group: group}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/appstack") {
+			fmt.Fprintf(w, `{"fs_DATABROWSE_F0092": {"data": {"gridData": {"rowset": [{"F0092_USER": "JDOE", "F0092_UGRP": %q}]}}}}`, s.group)
+			return
+		}
+
+		var req jde.AppStackRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("error decoding request: %v", err)
+		}
+
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		if req.Action != "open" && (req.StackID != 7 || req.StateID != len(s.requests) || req.Rid != "rid") {
+			t.Errorf("%s request on stack %d in state %d with rid %q, expected the state of the last response", req.Action, req.StackID, req.StateID, req.Rid)
+		}
+
+		request := req.Action
+		var actions []jde.FormAction
+		switch {
+		case req.FormRequest != nil:
+			request += " " + req.FormRequest.FormName + "/" + req.FormRequest.Version
+			actions = req.FormRequest.FormActions
+		case req.ActionRequest != nil:
+			request += " " + req.ActionRequest.FormOID
+			actions = req.ActionRequest.FormActions
+		}
+		request += ":"
+		for _, a := range actions {
+			request += " " + a.Command + " " + a.ControlID
+			if a.Command == jde.CommandSetQBEValue || a.Command == jde.CommandSetControlValue {
+				request += "=" + a.Value
+			}
+		}
+		s.requests = append(s.requests, request)
+
+		formErrors := "[]"
+		if s.failOn != "" && strings.HasPrefix(request, s.failOn) {
+			formErrors = `[{"CODE": "0002", "TITLE": "Invalid User Class/Group", "DESC": "The group does not exist."}]`
+		}
+		fmt.Fprintf(w, `{"stackId": 7, "stateId": %d, "rid": "rid", "fs_P0092_W0092A": {"title": "User Profile Revisions", "errors": %s}}`, len(s.requests), formErrors)
+	}))
+	return s
+}
+
+func TestGroupProvisioning(t *testing.T) {
+	const (
+		open    = "open P0092_W0092D/ZJDE0001: SetQBEValue 1[7]=JDOE DoAction 15"
+		selects = "execute W0092D: SelectRow 1.0 DoAction 4"
+		closes  = "close:"
+	)
+	setGroup := func(group string) string {
+		return "execute W0092A: SetControlValue 19=" + group + " DoAction 11"
+	}
+
+	tests := []struct {
+		name   string
+		group  string
+		revoke bool
+		failOn string
+		want   []string
+		err    string
+	}{
+		{name: "grant", group: "SALES", want: []string{open, selects, setGroup("ADMINS"), closes}},
+		{name: "grant to a member", group: "ADMINS"},
+		{name: "revoke", group: "ADMINS", revoke: true, want: []string{open, selects, setGroup(""), closes}},
+		{name: "revoke after the user moved", group: "SALES", revoke: true},
+		{
+			name: "form error", group: "SALES", failOn: "execute W0092A",
+			want: []string{open, selects, setGroup("ADMINS"), closes},
+			err:  "Invalid User Class/Group The group does not exist.",
+		},
+		{name: "error opening the form", group: "SALES", failOn: "open", want: []string{open, closes}, err: "error opening P0092_W0092D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newUserProfilesServer(t, tt.group)
+			srv.failOn = tt.failOn
+			defer srv.Close()
+
+			client, err := jde.NewClient(srv.Client(), srv.URL, jde.Credentials{AuthMode: jde.AuthModeBasic, Username: "u", Password: "p"}, jde.ClientOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			group, err := groupResource("ADMINS")
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, err := userResource(jde.User{ID: "JDOE"}, userDetails{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g := newGroupBuilder(client)
+			ctx := context.Background()
+			if tt.revoke {
+				_, err = g.Revoke(ctx, grant.NewGrant(group, groupMembership, user.Id))
+			} else {
+				_, err = g.Grant(ctx, user, ent.NewAssignmentEntitlement(group, groupMembership))
+			}
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatal(err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+
+			// the stack is closed whether the actions succeeded or not.
+			if strings.Join(srv.requests, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got application stack requests\n%s\nwant\n%s", strings.Join(srv.requests, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestGrantGroupToUsersOnly(t *testing.T) {
+	group, err := groupResource("ADMINS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := rs.NewRoleResource("ADMIN", roleResourceType, "ADMIN", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newGroupBuilder(nil).Grant(context.Background(), role, ent.NewAssignmentEntitlement(group, groupMembership)); err == nil {
+		t.Error("expected a role to be refused group membership")
+	}
+}
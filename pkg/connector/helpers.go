@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
@@ -12,16 +13,20 @@ func annotationsForUserResourceType() annotations.Annotations {
 	return annos
 }
 
-func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, string, bool, error) {
-	isInitial := true
+// annotationsForRateLimit reports the client side rate limit of the AIS client, if one is configured.
+func annotationsForRateLimit(client *jde.Client) annotations.Annotations {
+	annos := annotations.Annotations{}
+	if rl := client.RateLimit(); rl != nil {
+		annos.WithRateLimiting(rl)
+	}
+	return annos
+}
+
+func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, string, error) {
 	b := &pagination.Bag{}
 	err := b.Unmarshal(i)
 	if err != nil {
-		return nil, "", false, err
-	}
-
-	if i != "" {
-		isInitial = false
+		return nil, "", err
 	}
 
 	if b.Current() == nil {
@@ -31,5 +36,5 @@ func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, strin
 		})
 	}
 
-	return b, b.PageToken(), isInitial, nil
+	return b, b.PageToken(), nil
 }
@@ -0,0 +1,426 @@
+package connector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// GrantSyncMode selects how the members of roles are fetched.
+type GrantSyncMode string
+
+const (
+	// GrantSyncPerRole requests the members of every role on their own, in batches when AIS supports them.
+	GrantSyncPerRole GrantSyncMode = "per-role"
+	// GrantSyncBulk reads the whole role relationship table F95921 once per sync and answers the grants of every
+	// role from an index of it.
+	GrantSyncBulk GrantSyncMode = "bulk"
+)
+
+// ParseGrantSyncMode returns the GrantSyncMode named by mode, defaulting to GrantSyncPerRole.
+func ParseGrantSyncMode(mode string) (GrantSyncMode, error) {
+	switch GrantSyncMode(mode) {
+	case "", GrantSyncPerRole:
+		return GrantSyncPerRole, nil
+	case GrantSyncBulk:
+		return GrantSyncBulk, nil
+	default:
+		return "", fmt.Errorf("unsupported grant sync mode %q, expected %q or %q", mode, GrantSyncPerRole, GrantSyncBulk)
+	}
+}
+
+const (
+	// defaultSpillThreshold is the number of role assignments kept in memory, the index moves to temporary files
+	// beyond it.
+	defaultSpillThreshold = 100_000
+	// defaultIndexPageSize is the number of grants returned at once from the index.
+	defaultIndexPageSize = 1000
+)
+
+// roleIndex holds every role assignment of F95921, loaded on first use in every sync. Assignments are kept in
+// memory until there are more than spillThreshold of them. Beyond that, every spillThreshold assignments are sorted
+// by role and written to a temporary run file, and the runs are merged into a single file grouped by role once the
+// table was read, so memory stays bounded whatever order AIS returns rows in. The assignments of a role are sorted by
+// user, so that the assignments of a user, one per effective date, are returned in the same page.
+type roleIndex struct {
+	client         *jde.Client
+	spillThreshold int
+	pageSize       int
+
+	mtx    sync.Mutex
+	loaded bool
+	// members holds the assignments of every role until they are spilled.
+	members map[string][]jde.RoleUser
+	// runs hold the assignments spilled while loading, each sorted by role.
+	runs []*os.File
+	// file holds the spilled assignments as JSON lines, spans locates the lines of every role.
+	file    *os.File
+	spans   map[string]span
+	written int64
+}
+
+// span locates the assignments of a role in the spill file.
+type span struct {
+	offset int64
+	size   int64
+}
+
+func newRoleIndex(client *jde.Client) *roleIndex {
+	return &roleIndex{
+		client:         client,
+		spillThreshold: defaultSpillThreshold,
+		pageSize:       defaultIndexPageSize,
+	}
+}
+
+// invalidate drops the assignments loaded, so that the next page reads F95921 again. It is called when a new sync
+// starts listing roles.
+func (x *roleIndex) invalidate() {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	x.reset()
+	x.loaded = false
+}
+
+// page hands up to pageSize members of the role to emit, starting at the position of the token, and returns the
+// token of the next page.
+func (x *roleIndex) page(ctx context.Context, roleID string, token string, emit func(jde.RoleUser) error) (string, error) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	if err := x.load(ctx); err != nil {
+		return "", err
+	}
+
+	var pos int64
+	if token != "" {
+		var err error
+		pos, err = strconv.ParseInt(token, 10, 64)
+		if err != nil || pos < 0 {
+			return "", fmt.Errorf("invalid grant page token %q", token)
+		}
+	}
+
+	var next int64
+	var err error
+	if x.file == nil {
+		next, err = x.pageMemory(roleID, pos, emit)
+	} else {
+		next, err = x.pageFile(roleID, pos, emit)
+	}
+	if err != nil || next < 0 {
+		return "", err
+	}
+
+	return strconv.FormatInt(next, 10), nil
+}
+
+// pageMemory pages through the members held in memory, positions are indexes of members. It returns -1 after the
+// last member.
+func (x *roleIndex) pageMemory(roleID string, pos int64, emit func(jde.RoleUser) error) (int64, error) {
+	members := x.members[roleID]
+	if pos > int64(len(members)) {
+		return 0, fmt.Errorf("grant page token is past the members of role %s", roleID)
+	}
+
+	// pages end between users, so that the assignments of a user are returned together.
+	end := pos + int64(x.pageSize)
+	for end < int64(len(members)) && members[end].User == members[end-1].User {
+		end++
+	}
+	if end >= int64(len(members)) {
+		end = -1
+	}
+
+	page := members[pos:]
+	if end >= 0 {
+		page = members[pos:end]
+	}
+	for _, user := range page {
+		if err := emit(user); err != nil {
+			return 0, err
+		}
+	}
+
+	return end, nil
+}
+
+// pageFile pages through the members spilled to the file, positions are byte offsets within the span of the role.
+// It returns -1 after the last member.
+func (x *roleIndex) pageFile(roleID string, pos int64, emit func(jde.RoleUser) error) (int64, error) {
+	s := x.spans[roleID]
+	if pos > s.size {
+		return 0, fmt.Errorf("grant page token is past the members of role %s", roleID)
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(x.file, s.offset+pos, s.size-pos))
+	last := ""
+	for n := 0; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			return -1, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error reading members of role %s: %w", roleID, err)
+		}
+
+		var user jde.RoleUser
+		if err := json.Unmarshal(line, &user); err != nil {
+			return 0, fmt.Errorf("error decoding members of role %s: %w", roleID, err)
+		}
+		// pages end between users, so that the assignments of a user are returned together.
+		if n >= x.pageSize && user.User != last {
+			return pos, nil
+		}
+		pos += int64(len(line))
+		last = user.User
+
+		if err := emit(user); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// load reads F95921 once per sync.
+func (x *roleIndex) load(ctx context.Context) error {
+	if x.loaded {
+		return nil
+	}
+
+	x.members = make(map[string][]jde.RoleUser)
+	count := 0
+	buffered := 0
+	err := x.client.RoleRelationships().ForEach(ctx, func(user jde.RoleUser) error {
+		count++
+		buffered++
+		x.members[user.Role] = append(x.members[user.Role], user)
+		if buffered <= x.spillThreshold {
+			return nil
+		}
+
+		buffered = 0
+		return x.spill()
+	})
+	if err == nil && len(x.runs) > 0 {
+		if buffered > 0 {
+			err = x.spill()
+		}
+		if err == nil {
+			err = x.merge()
+		}
+	}
+	if err != nil {
+		x.reset()
+		return fmt.Errorf("error loading role assignments: %w", err)
+	}
+	for _, members := range x.members {
+		sortByUser(members)
+	}
+
+	ctxzap.Extract(ctx).Info("baton-jd-edwards: loaded role assignments",
+		zap.Int("assignments", count),
+		zap.Bool("spilled", x.file != nil),
+	)
+	x.loaded = true
+
+	return nil
+}
+
+// spill writes the members held in memory to a new run file, sorted by role.
+func (x *roleIndex) spill() error {
+	f, err := os.CreateTemp("", "baton-jd-edwards-roles-run-*.jsonl")
+	if err != nil {
+		return err
+	}
+	x.runs = append(x.runs, f)
+
+	roles := make([]string, 0, len(x.members))
+	for role := range x.members {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, role := range roles {
+		sortByUser(x.members[role])
+		for _, user := range x.members[role] {
+			if err := enc.Encode(user); err != nil {
+				return err
+			}
+		}
+	}
+	x.members = make(map[string][]jde.RoleUser)
+
+	return w.Flush()
+}
+
+// sortByUser sorts the assignments of a role by user, keeping the order they were read in for every user.
+func sortByUser(members []jde.RoleUser) {
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].User < members[j].User
+	})
+}
+
+// run reads back the assignments of a run file in order.
+type run struct {
+	r    *bufio.Reader
+	line []byte
+	user jde.RoleUser
+}
+
+// next reads the following assignment of the run, and reports false once it was exhausted.
+func (r *run) next() (bool, error) {
+	line, err := r.r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) && len(line) == 0 {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.line = line
+	r.user = jde.RoleUser{}
+	return true, json.Unmarshal(line, &r.user)
+}
+
+// merge merges the runs into the spill file, grouping the assignments by role then by user, and removes them.
+// Assignments of a user keep the order they were read in.
+func (x *roleIndex) merge() error {
+	f, err := os.CreateTemp("", "baton-jd-edwards-roles-*.jsonl")
+	if err != nil {
+		return err
+	}
+	x.file = f
+	x.spans = make(map[string]span)
+	w := bufio.NewWriter(f)
+
+	var heads []*run
+	for _, f := range x.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r := &run{r: bufio.NewReader(f)}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heads = append(heads, r)
+		}
+	}
+
+	current := ""
+	for len(heads) > 0 {
+		// runs are in reading order, the first run holding the smallest assignment keeps the order of its members.
+		i := 0
+		for n, r := range heads[1:] {
+			if r.user.Role < heads[i].user.Role || r.user.Role == heads[i].user.Role && r.user.User < heads[i].user.User {
+				i = n + 1
+			}
+		}
+
+		r := heads[i]
+		if err := x.write(w, &current, r.user.Role, r.line); err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			heads = append(heads[:i], heads[i+1:]...)
+		}
+	}
+
+	if err := x.flush(w); err != nil {
+		return err
+	}
+	return x.removeRuns()
+}
+
+// write appends the JSON line of an assignment of the role to the spill file. Assignments must be grouped by role.
+func (x *roleIndex) write(w *bufio.Writer, current *string, role string, line []byte) error {
+	if _, ok := x.spans[role]; !ok {
+		x.spans[role] = span{offset: x.written}
+	} else if role != *current {
+		return fmt.Errorf("role assignments of %s aren't grouped together", role)
+	}
+	*current = role
+
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+
+	s := x.spans[role]
+	s.size += int64(len(line))
+	x.spans[role] = s
+	x.written += int64(len(line))
+
+	return nil
+}
+
+// flush writes the buffered assignments to the spill file.
+func (x *roleIndex) flush(w *bufio.Writer) error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return x.file.Sync()
+}
+
+// reset drops what was loaded so far.
+func (x *roleIndex) reset() {
+	_ = x.removeRuns()
+	_ = x.removeFile()
+	x.members = nil
+	x.spans = nil
+	x.written = 0
+}
+
+// Close removes the temporary files, if any.
+func (x *roleIndex) Close() error {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	return errors.Join(x.removeRuns(), x.removeFile())
+}
+
+func (x *roleIndex) removeFile() error {
+	if x.file == nil {
+		return nil
+	}
+
+	err := removeTemp(x.file)
+	x.file = nil
+	return err
+}
+
+func (x *roleIndex) removeRuns() error {
+	var errs []error
+	for _, f := range x.runs {
+		errs = append(errs, removeTemp(f))
+	}
+	x.runs = nil
+	return errors.Join(errs...)
+}
+
+// removeTemp closes and removes a temporary file.
+func removeTemp(f *os.File) error {
+	err := f.Close()
+	if rmErr := os.Remove(f.Name()); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+)
+
+// newRelationshipServer serves the F95921 rows of assignments, "ROLE:USER" pairs, in a single page.
+func newRelationshipServer(assignments *[]string, mtx *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		var rows []string
+		for _, a := range *assignments {
+			role, user, _ := strings.Cut(a, ":")
+			rows = append(rows, fmt.Sprintf(`{"F95921_FRROLE": %q, "F95921_TOROLE": %q}`, role, user))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"fs_DATABROWSE_F95921": {"data": {"gridData": {"rowset": [%s]}}}}`, strings.Join(rows, ","))
+	}))
+}
+
+// members pages through the members of the role, one call to page per page, and returns them along with the
+// tokens handed out.
+func members(t *testing.T, x *roleIndex, roleID string, token string) ([]string, []string) {
+	t.Helper()
+
+	var users, tokens []string
+	for {
+		var err error
+		token, err = x.page(context.Background(), roleID, token, func(user jde.RoleUser) error {
+			if user.Role != roleID {
+				t.Errorf("got a member of %s while paging %s", user.Role, roleID)
+			}
+			users = append(users, user.User)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token == "" {
+			return users, tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func TestRoleIndexSpill(t *testing.T) {
+	// roles are interleaved, as AIS returns them without ordering.
+	assignments := []string{"R2:A", "R1:A", "R3:A", "R1:B", "R2:B", "R1:C", "R1:D", "R3:B", "R1:E", "R2:C"}
+	var mtx sync.Mutex
+	srv := newRelationshipServer(&assignments, &mtx)
+	defer srv.Close()
+
+	client, err := jde.NewClient(srv.Client(), srv.URL, jde.Credentials{AuthMode: jde.AuthModeBasic, Username: "u", Password: "p"}, jde.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := newRoleIndex(client)
+	x.spillThreshold = 3
+	x.pageSize = 2
+	defer x.Close()
+
+	users, tokens := members(t, x, "R1", "")
+	if x.file == nil || len(x.runs) != 0 {
+		t.Fatal("expected the assignments to be merged into the spill file")
+	}
+	if strings.Join(users, ",") != "A,B,C,D,E" || len(tokens) != 2 {
+		t.Errorf("got members %v of R1 with tokens %v", users, tokens)
+	}
+
+	// a token resumes the page where it left off.
+	resumed, _ := members(t, x, "R1", tokens[1])
+	if strings.Join(resumed, ",") != "E" {
+		t.Errorf("got members %v of R1 from token %s", resumed, tokens[1])
+	}
+
+	for role, want := range map[string]string{"R2": "A,B,C", "R3": "A,B", "R4": ""} {
+		if users, _ := members(t, x, role, ""); strings.Join(users, ",") != want {
+			t.Errorf("got members %v of %s, want %s", users, role, want)
+		}
+	}
+
+	// a new sync reads the assignments again.
+	mtx.Lock()
+	assignments = []string{"R1:F"}
+	mtx.Unlock()
+	x.invalidate()
+
+	users, _ = members(t, x, "R1", "")
+	if strings.Join(users, ",") != "F" || x.file != nil {
+		t.Errorf("got members %v of R1 after invalidating the index", users)
+	}
+}
+
+func TestRoleIndexKeepsUsersTogether(t *testing.T) {
+	// B and A are assigned R1 twice, with different effective dates.
+	assignments := []string{"R1:B", "R1:A", "R2:A", "R1:B", "R1:C", "R1:A", "R1:D"}
+	var mtx sync.Mutex
+	srv := newRelationshipServer(&assignments, &mtx)
+	defer srv.Close()
+
+	client, err := jde.NewClient(srv.Client(), srv.URL, jde.Credentials{AuthMode: jde.AuthModeBasic, Username: "u", Password: "p"}, jde.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, spillThreshold := range map[string]int{"memory": defaultSpillThreshold, "spilled": 2} {
+		t.Run(name, func(t *testing.T) {
+			x := newRoleIndex(client)
+			x.spillThreshold = spillThreshold
+			x.pageSize = 3
+			defer x.Close()
+
+			// the first page runs past its size to hold both assignments of B.
+			users, tokens := members(t, x, "R1", "")
+			if strings.Join(users, ",") != "A,A,B,B,C,D" || len(tokens) != 1 {
+				t.Fatalf("got members %v of R1 with tokens %v", users, tokens)
+			}
+			if resumed, _ := members(t, x, "R1", tokens[0]); strings.Join(resumed, ",") != "C,D" {
+				t.Errorf("got members %v of R1 from token %s", resumed, tokens[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package connector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+)
+
+// roleBatchSize is the number of roles whose members are requested at once.
+const roleBatchSize = 25
+
+// roleMembers fetches the first page of members of several roles in one AIS request, instead of one request per
+// role. Roles are batched in the order they were listed in; the pages of the other roles of a batch are kept until
+// their grants are synced.
+type roleMembers struct {
+	client *jde.Client
+
+	mtx sync.Mutex
+	// pending holds the roles listed whose members weren't requested yet, in listing order.
+	pending []string
+	pages   map[string]roleMembersPage
+}
+
+type roleMembersPage struct {
+	users []jde.RoleUser
+	token string
+}
+
+func newRoleMembers(client *jde.Client) *roleMembers {
+	return &roleMembers{
+		client: client,
+		pages:  make(map[string]roleMembersPage),
+	}
+}
+
+// queue records listed roles, whose members are requested along with the roles listed before them.
+func (m *roleMembers) queue(roleIDs ...string) {
+	// without batches, or keys to page batched queries by, every role is requested on its own.
+	if features := m.client.Features(); !features.Batch || !features.Aggregation {
+		return
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.pending = append(m.pending, roleIDs...)
+}
+
+// reset drops the roles queued and the pages fetched, which belong to a previous sync.
+func (m *roleMembers) reset() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.pending = nil
+	m.pages = make(map[string]roleMembersPage)
+}
+
+// firstPage hands the first page of members of the role to emit and returns the token of the next page.
+func (m *roleMembers) firstPage(ctx context.Context, roleID string, emit func(jde.RoleUser) error) (string, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	page, ok := m.pages[roleID]
+	if !ok {
+		var err error
+		page, err = m.fetch(ctx, roleID)
+		if err != nil {
+			return "", err
+		}
+	}
+	delete(m.pages, roleID)
+
+	for _, user := range page.users {
+		if err := emit(user); err != nil {
+			return "", err
+		}
+	}
+
+	return page.token, nil
+}
+
+// fetch requests the members of the role along with the pending roles listed after it.
+func (m *roleMembers) fetch(ctx context.Context, roleID string) (roleMembersPage, error) {
+	roleIDs := []string{roleID}
+	for i, pending := range m.pending {
+		if pending != roleID {
+			continue
+		}
+		for _, next := range m.pending[i+1:] {
+			if len(roleIDs) == roleBatchSize {
+				break
+			}
+			if _, ok := m.pages[next]; !ok && next != roleID {
+				roleIDs = append(roleIDs, next)
+			}
+		}
+		break
+	}
+	m.dequeue(roleIDs)
+
+	pagers := make([]*jde.Pager[jde.RoleUser], len(roleIDs))
+	for i, id := range roleIDs {
+		pagers[i] = m.client.RoleUsers(id)
+	}
+
+	users := make([][]jde.RoleUser, len(roleIDs))
+	err := jde.NextBatch(ctx, m.client, pagers, func(i int, user jde.RoleUser) error {
+		users[i] = append(users[i], user)
+		return nil
+	})
+	if err != nil {
+		return roleMembersPage{}, err
+	}
+
+	for i, id := range roleIDs[1:] {
+		m.pages[id] = roleMembersPage{users: users[i+1], token: pagers[i+1].Token()}
+	}
+
+	return roleMembersPage{users: users[0], token: pagers[0].Token()}, nil
+}
+
+// dequeue removes the roles from the pending roles.
+func (m *roleMembers) dequeue(roleIDs []string) {
+	fetched := make(map[string]bool, len(roleIDs))
+	for _, id := range roleIDs {
+		fetched[id] = true
+	}
+
+	pending := m.pending[:0]
+	for _, id := range m.pending {
+		if !fetched[id] {
+			pending = append(pending, id)
+		}
+	}
+	m.pending = pending
+}
@@ -0,0 +1,50 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestRoleListQueuesMembers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"fs_DATABROWSE_F00926": {"data": {"gridData": {"rowset": [{"F00926_USER": "R1"}, {"F00926_USER": "R2"}]}}}}`)
+	}))
+	defer srv.Close()
+
+	client, err := jde.NewClient(srv.Client(), srv.URL, jde.Credentials{AuthMode: jde.AuthModeBasic, Username: "u", Password: "p"}, jde.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.UseFeatures(jde.NewFeatures("v2", jde.ConfigResponse{
+		CapabilityList: []jde.CapabilityList{{Name: "dataServiceBatch"}, {Name: "dataServiceAggregation"}},
+	}))
+
+	ctx := context.Background()
+	r := newRoleBuilder(client, nil, EffectiveDatesFlag)
+	r.members.pages["R0"] = roleMembersPage{}
+	// every sync lists the roles from the first page, the roles and pages of the previous sync are dropped.
+	for i := 0; i < 2; i++ {
+		if _, _, _, err := r.List(ctx, nil, &pagination.Token{}); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(r.members.pending) != "[R1 R2]" || len(r.members.pages) != 0 {
+			t.Errorf("got pending roles %v and pages %v after listing roles", r.members.pending, r.members.pages)
+		}
+	}
+
+	bulk := newRoleBuilder(client, newRoleIndex(client), EffectiveDatesFlag)
+	defer bulk.index.Close()
+	if _, _, _, err := bulk.List(ctx, nil, &pagination.Token{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(bulk.members.pending) != 0 {
+		t.Errorf("expected no roles queued when grants are answered from the index, got %v", bulk.members.pending)
+	}
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/conductorone/baton-jd-edwards/pkg/jde"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -16,9 +17,109 @@ import (
 type roleBuilder struct {
 	resourceType *v2.ResourceType
 	client       *jde.Client
+	members      *roleMembers
+	// index answers grants from the whole role relationship table in GrantSyncBulk, it is nil otherwise.
+	index          *roleIndex
+	effectiveDates EffectiveDatesMode
 }
 
-const roleMembership = "member"
+const (
+	roleMembership = "member"
+	// roleMembershipInAll is granted along with roleMembership when the role is included in *ALL and the assignment
+	// is in effect, so it is active whenever the user signs in with *ALL instead of only when signing in with the
+	// role itself.
+	roleMembershipInAll = "member_in_all"
+)
+
+// EffectiveDatesMode selects what happens to the role assignments that aren't in effect today.
+type EffectiveDatesMode string
+
+const (
+	// EffectiveDatesFlag syncs expired and future assignments as grants, their status is in the grant metadata.
+	EffectiveDatesFlag EffectiveDatesMode = "flag"
+	// EffectiveDatesDrop only syncs the assignments in effect today.
+	EffectiveDatesDrop EffectiveDatesMode = "drop"
+)
+
+// ParseEffectiveDatesMode returns the EffectiveDatesMode named by mode, defaulting to EffectiveDatesFlag.
+func ParseEffectiveDatesMode(mode string) (EffectiveDatesMode, error) {
+	switch EffectiveDatesMode(mode) {
+	case "", EffectiveDatesFlag:
+		return EffectiveDatesFlag, nil
+	case EffectiveDatesDrop:
+		return EffectiveDatesDrop, nil
+	default:
+		return "", fmt.Errorf("unsupported effective dates mode %q, expected %q or %q", mode, EffectiveDatesFlag, EffectiveDatesDrop)
+	}
+}
+
+// Statuses of a role assignment, by preference when a user is assigned a role more than once.
+const (
+	assignmentActive  = "active"
+	assignmentFuture  = "future"
+	assignmentExpired = "expired"
+)
+
+// assignmentStatus tells whether a role assignment is in effect today, will be, or has lapsed.
+func assignmentStatus(user jde.RoleUser, now time.Time) string {
+	switch {
+	case user.Effective(now):
+		return assignmentActive
+	case !user.EffectiveThru.IsZero() && now.After(user.EffectiveThru):
+		return assignmentExpired
+	default:
+		return assignmentFuture
+	}
+}
+
+// preferredAssignment returns the assignment that stands for the membership of a user assigned a role once per
+// effective date range: the one in effect, otherwise the next one to take effect, otherwise the last one to lapse.
+func preferredAssignment(assignments []jde.RoleUser, now time.Time) jde.RoleUser {
+	rank := map[string]int{assignmentActive: 0, assignmentFuture: 1, assignmentExpired: 2}
+
+	best := assignments[0]
+	for _, a := range assignments[1:] {
+		status, bestStatus := assignmentStatus(a, now), assignmentStatus(best, now)
+		switch {
+		case rank[status] < rank[bestStatus]:
+			best = a
+		case status == bestStatus && status == assignmentFuture && a.EffectiveFrom.Before(best.EffectiveFrom):
+			best = a
+		case status == bestStatus && status == assignmentExpired && a.EffectiveThru.After(best.EffectiveThru):
+			best = a
+		}
+	}
+	return best
+}
+
+// effectiveDates describes when a role assignment is in effect.
+func effectiveDates(user jde.RoleUser, metadata map[string]interface{}) map[string]interface{} {
+	if !user.EffectiveFrom.IsZero() {
+		metadata["effective_from"] = user.EffectiveFrom.Format(time.DateOnly)
+	}
+	if !user.EffectiveThru.IsZero() {
+		metadata["effective_thru"] = user.EffectiveThru.Format(time.DateOnly)
+	}
+	return metadata
+}
+
+// assignmentMetadata describes when the preferred assignment of a user is in effect, whether it is today, and
+// whether and in which order it is active with *ALL. Every assignment of the user is listed in effective_ranges.
+func assignmentMetadata(user jde.RoleUser, assignments []jde.RoleUser, now time.Time) map[string]interface{} {
+	ranges := make([]interface{}, 0, len(assignments))
+	for _, a := range assignments {
+		ranges = append(ranges, effectiveDates(a, map[string]interface{}{
+			"assignment_status": assignmentStatus(a, now),
+		}))
+	}
+
+	return effectiveDates(user, map[string]interface{}{
+		"assignment_status": assignmentStatus(user, now),
+		"include_in_all":    user.IncludeInAll,
+		"sequence":          user.Sequence,
+		"effective_ranges":  ranges,
+	})
+}
 
 func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return r.resourceType
@@ -49,48 +150,43 @@ func roleResource(role, roleDescription string) (*v2.Resource, error) {
 }
 
 func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	bag, page, isInitial, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: roleResourceType.Id})
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: roleResourceType.Id})
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	var allRoles []jde.Columns
-	var nextToken string
-
-	if page == "" && isInitial {
-		roles, nextUrl, err := r.client.ListRoles(ctx, "100")
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error fetching roles: %w", err)
-		}
-		allRoles = append(allRoles, roles...)
-
-		nextToken, err = bag.NextToken(nextUrl)
-		if err != nil {
-			return nil, "", nil, err
-		}
-	} else {
-		roles, nextUrl, err := r.client.FetchMoreRoles(ctx, page)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error fetching roles: %w", err)
-		}
-		allRoles = append(allRoles, roles...)
-
-		nextToken, err = bag.NextToken(nextUrl)
-		if err != nil {
-			return nil, "", nil, err
+	// a new sync starts, role assignments are read again.
+	if page == "" {
+		r.members.reset()
+		if r.index != nil {
+			r.index.invalidate()
 		}
 	}
 
 	var rv []*v2.Resource
-	for _, role := range allRoles {
-		rr, err := roleResource(role.F00926User, role.F00926RoleDesc)
+	pager := r.client.Roles().Resume(page)
+	err = pager.NextFunc(ctx, func(role jde.Role) error {
+		rr, err := roleResource(role.ID, role.Description)
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("error creating role resource: %w", err)
+			return fmt.Errorf("error creating role resource: %w", err)
 		}
 		rv = append(rv, rr)
+		// grants are answered from the index, members aren't requested per role.
+		if r.index == nil {
+			r.members.queue(role.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error fetching roles: %w", err)
 	}
 
-	return rv, nextToken, nil, nil
+	nextToken, err := bag.NextToken(pager.Token())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextToken, annotationsForRateLimit(r.client), nil
 }
 
 func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -108,59 +204,99 @@ func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 		assignmentOptions...,
 	))
 
+	inAllOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("%s Role %s", resource.DisplayName, roleMembershipInAll)),
+		ent.WithDescription(fmt.Sprintf("Member of %s role in JD Edwards EnterpriseOne, active when signing in with *ALL", resource.DisplayName)),
+	}
+
+	rv = append(rv, ent.NewAssignmentEntitlement(
+		resource,
+		roleMembershipInAll,
+		inAllOptions...,
+	))
+
 	return rv, "", nil, nil
 }
 
 func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	bag, page, isInitial, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: userResourceType.Id})
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: userResourceType.Id})
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	var allUsers []jde.Columns
-	var nextToken string
-
-	if page == "" && isInitial {
-		users, nextUrl, err := r.client.ListRoleUsers(ctx, resource.Id.Resource, "100")
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error fetching role users: %w", err)
+	// users are assigned a role once per effective date range, their assignments are granted together. Pages never
+	// split the assignments of a user.
+	now := time.Now()
+	var users []string
+	assignments := make(map[string][]jde.RoleUser)
+	emit := func(user jde.RoleUser) error {
+		if r.effectiveDates == EffectiveDatesDrop && !user.Effective(now) {
+			return nil
 		}
-		allUsers = append(allUsers, users...)
-		nextToken, err = bag.NextToken(nextUrl)
-		if err != nil {
-			return nil, "", nil, err
-		}
-	} else {
-		users, nextUrl, err := r.client.FetchMoreRoleUsers(ctx, page)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error fetching role users: %w", err)
-		}
-		allUsers = append(allUsers, users...)
-		nextToken, err = bag.NextToken(nextUrl)
-		if err != nil {
-			return nil, "", nil, err
+
+		if _, ok := assignments[user.User]; !ok {
+			users = append(users, user.User)
 		}
+		assignments[user.User] = append(assignments[user.User], user)
+		return nil
+	}
+
+	// the first page of members is requested in a batch with the roles listed after this one.
+	var next string
+	switch {
+	case r.index != nil:
+		next, err = r.index.page(ctx, resource.Id.Resource, page, emit)
+	case page == "":
+		next, err = r.members.firstPage(ctx, resource.Id.Resource, emit)
+	default:
+		pager := r.client.RoleUsers(resource.Id.Resource).Resume(page)
+		err = pager.NextFunc(ctx, emit)
+		next = pager.Token()
+	}
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error fetching role users: %w", err)
 	}
 
 	var rv []*v2.Grant
-	for _, user := range allUsers {
-		ur, err := userResource(user.F95921ToRole)
+	for _, id := range users {
+		user := preferredAssignment(assignments[id], now)
+		userID, err := rs.NewResourceID(userResourceType, user.User)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("error creating user resource for role %s: %w", resource.Id.Resource, err)
 		}
 
+		metadata := assignmentMetadata(user, assignments[id], now)
 		rv = append(rv, grant.NewGrant(
 			resource,
 			roleMembership,
-			ur.Id,
+			userID,
+			grant.WithGrantMetadata(metadata),
 		))
+		if user.IncludeInAll && user.Effective(now) {
+			rv = append(rv, grant.NewGrant(
+				resource,
+				roleMembershipInAll,
+				userID,
+				grant.WithGrantMetadata(metadata),
+			))
+		}
+	}
+
+	nextToken, err := bag.NextToken(next)
+	if err != nil {
+		return nil, "", nil, err
 	}
-	return rv, nextToken, nil, nil
+
+	return rv, nextToken, annotationsForRateLimit(r.client), nil
 }
 
-func newRoleBuilder(client *jde.Client) *roleBuilder {
+func newRoleBuilder(client *jde.Client, index *roleIndex, effectiveDates EffectiveDatesMode) *roleBuilder {
 	return &roleBuilder{
-		resourceType: roleResourceType,
-		client:       client,
+		resourceType:   roleResourceType,
+		client:         client,
+		members:        newRoleMembers(client),
+		index:          index,
+		effectiveDates: effectiveDates,
 	}
 }
@@ -0,0 +1,149 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestAssignmentMetadataStatus(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		user jde.RoleUser
+		want string
+	}{
+		{"no dates", jde.RoleUser{}, "active"},
+		{"within dates", jde.RoleUser{EffectiveFrom: day(1, 1), EffectiveThru: day(12, 31)}, "active"},
+		{"last day", jde.RoleUser{EffectiveThru: day(3, 1)}, "active"},
+		{"starts tomorrow", jde.RoleUser{EffectiveFrom: day(3, 2)}, "future"},
+		{"ended yesterday", jde.RoleUser{EffectiveThru: day(2, 29)}, "expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignmentStatus(tt.user, now); got != tt.want {
+				t.Errorf("got assignment status %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferredAssignment(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	expired := jde.RoleUser{User: "expired", EffectiveThru: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)}
+	lastExpired := jde.RoleUser{User: "last expired", EffectiveThru: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}
+	active := jde.RoleUser{User: "active", EffectiveThru: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)}
+	future := jde.RoleUser{User: "future", EffectiveFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	nextFuture := jde.RoleUser{User: "next future", EffectiveFrom: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name        string
+		assignments []jde.RoleUser
+		want        string
+	}{
+		{"in effect", []jde.RoleUser{expired, active, future}, "active"},
+		{"renewed", []jde.RoleUser{expired, future}, "future"},
+		{"next to take effect", []jde.RoleUser{future, nextFuture}, "next future"},
+		{"last to lapse", []jde.RoleUser{expired, lastExpired}, "last expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferredAssignment(tt.assignments, now).User; got != tt.want {
+				t.Errorf("got assignment %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleGrantsEffectiveDates(t *testing.T) {
+	// ACTIVE is in effect and included in *ALL, EXPIRED and FUTURE aren't in effect today, EXPIRED was included in
+	// *ALL until it lapsed. RENEWED was assigned the
+	// role again after the first assignment lapsed.
+	rows := []string{
+		`{"F95921_FRROLE": "ADMIN", "F95921_TOROLE": "RENEWED", "F95921_EFFFROM": "20000101", "F95921_EFFTHRU": "20010101"}`,
+		`{"F95921_FRROLE": "ADMIN", "F95921_TOROLE": "RENEWED", "F95921_EFFFROM": "20010102", "F95921_EFFTHRU": ""}`,
+		`{"F95921_FRROLE": "ADMIN", "F95921_TOROLE": "ACTIVE", "F95921_EFFFROM": "20000101", "F95921_EFFTHRU": "20991231", "F95921_FUSE": "1"}`,
+		`{"F95921_FRROLE": "ADMIN", "F95921_TOROLE": "EXPIRED", "F95921_EFFFROM": "20000101", "F95921_EFFTHRU": "20010101", "F95921_FUSE": "1"}`,
+		`{"F95921_FRROLE": "ADMIN", "F95921_TOROLE": "FUTURE", "F95921_EFFFROM": "20990101", "F95921_EFFTHRU": ""}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"fs_DATABROWSE_F95921": {"data": {"gridData": {"rowset": [%s]}}}}`, strings.Join(rows, ","))
+	}))
+	defer srv.Close()
+
+	client, err := jde.NewClient(srv.Client(), srv.URL, jde.Credentials{AuthMode: jde.AuthModeBasic, Username: "u", Password: "p"}, jde.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := roleResource("ADMIN", "Administrators")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode EffectiveDatesMode
+		want string
+	}{
+		{EffectiveDatesFlag, "ACTIVE:member,ACTIVE:member_in_all,EXPIRED:member,FUTURE:member,RENEWED:member"},
+		{EffectiveDatesDrop, "ACTIVE:member,ACTIVE:member_in_all,RENEWED:member"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			grants, next, _, err := newRoleBuilder(client, nil, tt.mode).Grants(context.Background(), role, &pagination.Token{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next != "" {
+				t.Errorf("expected a single page of grants, got token %q", next)
+			}
+
+			var got []string
+			for _, g := range grants {
+				slug := g.Entitlement.Id[strings.LastIndex(g.Entitlement.Id, ":")+1:]
+				got = append(got, g.Principal.Id.Resource+":"+slug)
+
+				if g.Principal.Id.Resource != "RENEWED" {
+					continue
+				}
+				metadata := &v2.GrantMetadata{}
+				annos := annotations.Annotations(g.Annotations)
+				if _, err := annos.Pick(metadata); err != nil {
+					t.Fatal(err)
+				}
+				fields := metadata.Metadata.GetFields()
+				if status := fields["assignment_status"].GetStringValue(); status != assignmentActive {
+					t.Errorf("got status %s for the renewed assignment", status)
+				}
+				ranges := 2
+				if tt.mode == EffectiveDatesDrop {
+					ranges = 1
+				}
+				if n := len(fields["effective_ranges"].GetListValue().GetValues()); n != ranges {
+					t.Errorf("got %d effective ranges for the renewed assignment, want %d", n, ranges)
+				}
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("got grants %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
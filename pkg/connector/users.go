@@ -3,6 +3,9 @@ package connector
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/conductorone/baton-jd-edwards/pkg/jde"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -20,21 +23,63 @@ func (u *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return u.resourceType
 }
 
-// Create a new connector resource for a JD Edwards user.
-func userResource(user string) (*v2.Resource, error) {
+// userDetails holds the address book entries, email addresses and sign-on security of a page of users.
+type userDetails struct {
+	entries  map[int64]jde.AddressBookEntry
+	emails   map[int64][]jde.ElectronicAddress
+	security map[string]jde.SignOnSecurity
+}
+
+// Create a new connector resource for a JD Edwards user, named after its address book entry if it has one.
+func userResource(user jde.User, details userDetails) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"user_id": user,
+		"user_id": user.ID,
+	}
+
+	displayName := user.ID
+	if entry, ok := details.entries[user.AddressNumber]; ok {
+		profile["address_number"] = user.AddressNumber
+		profile["search_type"] = entry.SearchType
+		if entry.AlphaName != "" {
+			displayName = entry.AlphaName
+			profile["alpha_name"] = entry.AlphaName
+		}
+		if last, first, ok := strings.Cut(entry.AlphaName, ","); ok {
+			profile["first_name"] = strings.TrimSpace(first)
+			profile["last_name"] = strings.TrimSpace(last)
+		}
 	}
 
 	userTraitOptions := []rs.UserTraitOption{
 		rs.WithUserProfile(profile),
-		rs.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
+	}
+
+	security, ok := details.security[user.ID]
+	if ok {
+		addSignOnSecurity(profile, security, time.Now())
+	}
+	status, statusDetails := userStatus(security, ok)
+	userTraitOptions = append(userTraitOptions, rs.WithDetailedStatus(status, statusDetails))
+
+	// the first email of the who's who line of the entry itself is the primary one.
+	emails := details.emails[user.AddressNumber]
+	sort.Slice(emails, func(i, j int) bool {
+		if emails[i].WhosWhoLine != emails[j].WhosWhoLine {
+			return emails[i].WhosWhoLine < emails[j].WhosWhoLine
+		}
+		return emails[i].Line < emails[j].Line
+	})
+	for i, email := range emails {
+		if i == 0 {
+			profile["email"] = email.Address
+		}
+		userTraitOptions = append(userTraitOptions, rs.WithEmail(email.Address, i == 0))
 	}
 
 	ret, err := rs.NewUserResource(
-		user,
+		displayName,
 		userResourceType,
-		user,
+		user.ID,
 		userTraitOptions,
 	)
 	if err != nil {
@@ -45,47 +90,123 @@ func userResource(user string) (*v2.Resource, error) {
 }
 
 func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	bag, page, isInitial, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: userResourceType.Id})
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: userResourceType.Id})
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	var allUsers []jde.Columns
-	var nextToken string
-	if page == "" && isInitial {
-		users, nextUrl, err := u.client.ListUsers(ctx, "100", true)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error fetching users: %w", err)
-		}
-		allUsers = append(allUsers, users...)
-
-		nextToken, err = bag.NextToken(nextUrl)
-		if err != nil {
-			return nil, "", nil, err
-		}
-	} else {
-		users, nextUrl, err := u.client.FetchMoreUsers(ctx, page)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error fetching users: %w", err)
-		}
-		allUsers = append(allUsers, users...)
+	pager := u.client.Users().Resume(page)
+	users, err := pager.Next(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error fetching users: %w", err)
+	}
 
-		nextToken, err = bag.NextToken(nextUrl)
-		if err != nil {
-			return nil, "", nil, err
-		}
+	details, err := u.details(ctx, users)
+	if err != nil {
+		return nil, "", nil, err
 	}
 
 	var rv []*v2.Resource
-	for _, user := range allUsers {
-		ur, err := userResource(user.F0092User)
+	for _, user := range users {
+		ur, err := userResource(user, details)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("error creating user resource: %w", err)
 		}
 		rv = append(rv, ur)
 	}
 
-	return rv, nextToken, nil, nil
+	nextToken, err := bag.NextToken(pager.Token())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextToken, annotationsForRateLimit(u.client), nil
+}
+
+// details looks up the address book entries and email addresses of the users in F0101 and F01151, and their
+// sign-on security in F98OWSEC.
+func (u *userBuilder) details(ctx context.Context, users []jde.User) (userDetails, error) {
+	details := userDetails{
+		entries:  make(map[int64]jde.AddressBookEntry),
+		emails:   make(map[int64][]jde.ElectronicAddress),
+		security: make(map[string]jde.SignOnSecurity),
+	}
+	if len(users) == 0 {
+		return details, nil
+	}
+
+	userIDs := make([]string, 0, len(users))
+	var addressNumbers []int64
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+		if user.AddressNumber != 0 {
+			addressNumbers = append(addressNumbers, user.AddressNumber)
+		}
+	}
+
+	err := u.client.SignOnSecurity(userIDs...).ForEach(ctx, func(security jde.SignOnSecurity) error {
+		details.security[security.User] = security
+		return nil
+	})
+	if err != nil {
+		return userDetails{}, fmt.Errorf("error fetching sign-on security: %w", err)
+	}
+
+	if len(addressNumbers) == 0 {
+		return details, nil
+	}
+
+	err = u.client.AddressBook(addressNumbers...).ForEach(ctx, func(entry jde.AddressBookEntry) error {
+		details.entries[entry.AddressNumber] = entry
+		return nil
+	})
+	if err != nil {
+		return userDetails{}, fmt.Errorf("error fetching address book entries: %w", err)
+	}
+
+	err = u.client.EmailAddresses(addressNumbers...).ForEach(ctx, func(email jde.ElectronicAddress) error {
+		if email.Address != "" {
+			details.emails[email.AddressNumber] = append(details.emails[email.AddressNumber], email)
+		}
+		return nil
+	})
+	if err != nil {
+		return userDetails{}, fmt.Errorf("error fetching email addresses: %w", err)
+	}
+
+	return details, nil
+}
+
+// userStatus maps the sign-on security of a user to its status. Users without sign-on security have no known status.
+func userStatus(security jde.SignOnSecurity, found bool) (v2.UserTrait_Status_Status, string) {
+	switch {
+	case !found:
+		return v2.UserTrait_Status_STATUS_UNSPECIFIED, "no sign-on security record"
+	case security.Status == jde.SignOnDisabled:
+		return v2.UserTrait_Status_STATUS_DISABLED, "disabled"
+	case security.LockedOut():
+		return v2.UserTrait_Status_STATUS_DISABLED, fmt.Sprintf("locked out after %d invalid sign-on attempts", security.InvalidAttempts)
+	default:
+		return v2.UserTrait_Status_STATUS_ENABLED, ""
+	}
+}
+
+// addSignOnSecurity adds the password age and expiry and the invalid sign-on attempts to the profile.
+func addSignOnSecurity(profile map[string]interface{}, security jde.SignOnSecurity, now time.Time) {
+	profile["sign_on_status"] = security.Status
+	profile["invalid_sign_on_attempts"] = security.InvalidAttempts
+	profile["allowed_sign_on_attempts"] = security.AllowedAttempts
+	profile["password_change_frequency_days"] = security.PasswordChangeFrequency
+
+	if !security.PasswordLastChanged.IsZero() {
+		profile["password_last_changed"] = security.PasswordLastChanged.Format(time.DateOnly)
+		profile["password_age_days"] = int64(now.Sub(security.PasswordLastChanged).Hours() / 24)
+	}
+
+	if expires := security.PasswordExpires(); !expires.IsZero() {
+		profile["password_expires"] = expires.Format(time.DateOnly)
+		profile["password_expired"] = now.After(expires)
+	}
 }
 
 // Entitlements always returns an empty slice for users.
@@ -0,0 +1,207 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-jd-edwards/pkg/jde"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+func TestUserStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		security jde.SignOnSecurity
+		found    bool
+		want     v2.UserTrait_Status_Status
+		details  string
+	}{
+		{"enabled", jde.SignOnSecurity{Status: jde.SignOnEnabled, AllowedAttempts: 3, InvalidAttempts: 2}, true, v2.UserTrait_Status_STATUS_ENABLED, ""},
+		{"disabled", jde.SignOnSecurity{Status: jde.SignOnDisabled}, true, v2.UserTrait_Status_STATUS_DISABLED, "disabled"},
+		{"locked out", jde.SignOnSecurity{Status: jde.SignOnEnabled, AllowedAttempts: 3, InvalidAttempts: 3}, true, v2.UserTrait_Status_STATUS_DISABLED, "locked out after 3 invalid sign-on attempts"},
+		{"unlimited attempts", jde.SignOnSecurity{Status: jde.SignOnEnabled, InvalidAttempts: 10}, true, v2.UserTrait_Status_STATUS_ENABLED, ""},
+		{"no sign-on security", jde.SignOnSecurity{}, false, v2.UserTrait_Status_STATUS_UNSPECIFIED, "no sign-on security record"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, details := userStatus(tt.security, tt.found)
+			if status != tt.want || details != tt.details {
+				t.Errorf("got status %s %q, want %s %q", status, details, tt.want, tt.details)
+			}
+		})
+	}
+}
+
+func TestAddSignOnSecurity(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	changed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		security jde.SignOnSecurity
+		want     map[string]interface{}
+	}{
+		{
+			"expired password",
+			jde.SignOnSecurity{PasswordChangeFrequency: 30, PasswordLastChanged: changed},
+			map[string]interface{}{"password_last_changed": "2024-01-01", "password_age_days": int64(60), "password_expires": "2024-01-31", "password_expired": true},
+		},
+		{
+			"valid password",
+			jde.SignOnSecurity{PasswordChangeFrequency: 90, PasswordLastChanged: changed},
+			map[string]interface{}{"password_last_changed": "2024-01-01", "password_age_days": int64(60), "password_expires": "2024-03-31", "password_expired": false},
+		},
+		{
+			"password that doesn't expire",
+			jde.SignOnSecurity{PasswordLastChanged: changed},
+			map[string]interface{}{"password_last_changed": "2024-01-01", "password_age_days": int64(60), "password_expires": nil, "password_expired": nil},
+		},
+		{
+			"password never changed",
+			jde.SignOnSecurity{PasswordChangeFrequency: 30},
+			map[string]interface{}{"password_last_changed": nil, "password_age_days": nil, "password_expires": nil, "password_expired": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := map[string]interface{}{}
+			addSignOnSecurity(profile, tt.security, now)
+			for key, want := range tt.want {
+				if got := profile[key]; got != want {
+					t.Errorf("got %s %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUserResource(t *testing.T) {
+	details := userDetails{
+		entries: map[int64]jde.AddressBookEntry{
+			1: {AddressNumber: 1, AlphaName: "Doe, John", SearchType: "E"},
+			2: {AddressNumber: 2, AlphaName: "Operations Desk", SearchType: "E"},
+		},
+		emails: map[int64][]jde.ElectronicAddress{
+			// the who's who line of the entry itself comes first, then the lines of its emails.
+			1: {
+				{AddressNumber: 1, WhosWhoLine: 1, Line: 0, Address: "assistant@example.com"},
+				{AddressNumber: 1, WhosWhoLine: 0, Line: 2, Address: "john.doe@example.com"},
+				{AddressNumber: 1, WhosWhoLine: 0, Line: 1, Address: "jdoe@example.com"},
+			},
+		},
+		security: map[string]jde.SignOnSecurity{},
+	}
+
+	tests := []struct {
+		name        string
+		user        jde.User
+		displayName string
+		first, last string
+		emails      string
+	}{
+		{"last, first", jde.User{ID: "JDOE", AddressNumber: 1}, "Doe, John", "John", "Doe", "*jdoe@example.com,john.doe@example.com,assistant@example.com"},
+		{"no comma", jde.User{ID: "OPS", AddressNumber: 2}, "Operations Desk", "", "", ""},
+		{"no address book entry", jde.User{ID: "SVC", AddressNumber: 0}, "SVC", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := userResource(tt.user, details)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.DisplayName != tt.displayName {
+				t.Errorf("got display name %q, want %q", r.DisplayName, tt.displayName)
+			}
+
+			trait, err := rs.GetUserTrait(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields := trait.Profile.GetFields()
+			if first, last := fields["first_name"].GetStringValue(), fields["last_name"].GetStringValue(); first != tt.first || last != tt.last {
+				t.Errorf("got first name %q and last name %q, want %q and %q", first, last, tt.first, tt.last)
+			}
+
+			var emails []string
+			for _, email := range trait.Emails {
+				if email.IsPrimary {
+					emails = append(emails, "*"+email.Address)
+					if fields["email"].GetStringValue() != email.Address {
+						t.Errorf("got email %q in the profile, want the primary %q", fields["email"].GetStringValue(), email.Address)
+					}
+					continue
+				}
+				emails = append(emails, email.Address)
+			}
+			if strings.Join(emails, ",") != tt.emails {
+				t.Errorf("got emails %v, want %s", emails, tt.emails)
+			}
+
+			// users without sign-on security have no known status.
+			if trait.Status.GetStatus() != v2.UserTrait_Status_STATUS_UNSPECIFIED {
+				t.Errorf("got status %s", trait.Status.GetStatus())
+			}
+		})
+	}
+}
+
+func TestUserDetailsWithoutAddressNumber(t *testing.T) {
+	var mtx sync.Mutex
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jde.DataRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("error decoding request: %v", err)
+		}
+		var values []string
+		for _, v := range req.Query.Condition[0].Value {
+			values = append(values, v.Content)
+		}
+		mtx.Lock()
+		requested = append(requested, req.TargetName+":"+strings.Join(values, "|"))
+		mtx.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"fs_DATABROWSE_%s": {"data": {"gridData": {"rowset": []}}}}`, req.TargetName)
+	}))
+	defer srv.Close()
+
+	client, err := jde.NewClient(srv.Client(), srv.URL, jde.Credentials{AuthMode: jde.AuthModeBasic, Username: "u", Password: "p"}, jde.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newUserBuilder(client)
+
+	tests := []struct {
+		name  string
+		users []jde.User
+		want  string
+	}{
+		// without address numbers, the address book isn't looked up.
+		{"no address numbers", []jde.User{{ID: "A"}, {ID: "B"}}, "F98OWSEC:A|B"},
+		{"some address numbers", []jde.User{{ID: "A"}, {ID: "B", AddressNumber: 5}}, "F98OWSEC:A|B,F0101:5,F01151:5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			if _, err := u.details(context.Background(), tt.users); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(requested, ",") != tt.want {
+				t.Errorf("got requests %v, want %s", requested, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const appstack = "appstack"
+
+// Application stack actions, see AppStackRequest.
+const (
+	appStackOpen    = "open"
+	appStackExecute = "execute"
+	appStackClose   = "close"
+)
+
+// Form action commands.
+const (
+	CommandSetQBEValue     = "SetQBEValue"
+	CommandSetControlValue = "SetControlValue"
+	CommandSelectRow       = "SelectRow"
+	CommandDoAction        = "DoAction"
+)
+
+// AppStackRequest drives an interactive application through a stack of forms that stays open on the AIS server
+// between requests. It is used to change records through the applications that own them, so that their business
+// logic runs, instead of writing tables directly.
+type AppStackRequest struct {
+	Action        string         `json:"action"`
+	FormRequest   *FormRequest   `json:"formRequest,omitempty"`
+	ActionRequest *ActionRequest `json:"actionRequest,omitempty"`
+	StackID       int            `json:"stackId,omitempty"`
+	StateID       int            `json:"stateId,omitempty"`
+	Rid           string         `json:"rid,omitempty"`
+	SessionFields
+}
+
+// FormRequest opens a form, e.g. P0092_W0092D, and runs the actions on it.
+type FormRequest struct {
+	FormName    string       `json:"formName"`
+	Version     string       `json:"version,omitempty"`
+	FormActions []FormAction `json:"formActions,omitempty"`
+}
+
+// ActionRequest runs actions on the form of the stack that is currently open.
+type ActionRequest struct {
+	FormOID     string       `json:"formOID"`
+	FormActions []FormAction `json:"formActions,omitempty"`
+}
+
+// FormAction sets a control, selects a grid row or presses a button.
+type FormAction struct {
+	Command   string `json:"command"`
+	ControlID string `json:"controlID"`
+	Value     string `json:"value,omitempty"`
+}
+
+// FormMessage is an error or a warning a form reported.
+type FormMessage struct {
+	Code  string `json:"CODE"`
+	Title string `json:"TITLE"`
+	Desc  string `json:"DESC"`
+}
+
+// AppStackResponse is the state of the stack after a request, along with the forms it displays.
+type AppStackResponse struct {
+	StackID int    `json:"stackId"`
+	StateID int    `json:"stateId"`
+	Rid     string `json:"rid"`
+	// Forms maps the "fs_<application>_<form>" keys of the response to the form they hold.
+	Forms map[string]FormResponse `json:"-"`
+}
+
+// FormResponse holds the messages of a form.
+type FormResponse struct {
+	Title    string        `json:"title"`
+	Errors   []FormMessage `json:"errors"`
+	Warnings []FormMessage `json:"warnings"`
+}
+
+func (r *AppStackResponse) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	type stack AppStackResponse
+	var s stack
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	s.Forms = make(map[string]FormResponse)
+
+	for key, value := range raw {
+		if !strings.HasPrefix(key, "fs_") {
+			continue
+		}
+		var form FormResponse
+		if err := json.Unmarshal(value, &form); err != nil {
+			return fmt.Errorf("error decoding form %s: %w", key, err)
+		}
+		s.Forms[key] = form
+	}
+
+	*r = AppStackResponse(s)
+	return nil
+}
+
+// formErrors returns the errors the forms of the response reported, if any.
+func (r AppStackResponse) formErrors() error {
+	var messages []string
+	for key, form := range r.Forms {
+		for _, e := range form.Errors {
+			messages = append(messages, fmt.Sprintf("%s: %s", strings.TrimPrefix(key, "fs_"), strings.TrimSpace(e.Title+" "+e.Desc)))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("form reported errors: %s", strings.Join(messages, "; "))
+}
+
+// AppStack is an application stack opened with OpenAppStack. It must be closed with Close.
+type AppStack struct {
+	c     *Client
+	state AppStackResponse
+}
+
+// OpenAppStack opens the form of the request on a new application stack.
+func (c *Client) OpenAppStack(ctx context.Context, form FormRequest) (*AppStack, error) {
+	s := &AppStack{c: c}
+	if err := s.send(ctx, AppStackRequest{Action: appStackOpen, FormRequest: &form}); err != nil {
+		// the stack may be open although the form reported errors.
+		if s.state.StackID != 0 {
+			_ = s.Close(ctx)
+		}
+		return nil, fmt.Errorf("error opening %s: %w", form.FormName, err)
+	}
+	return s, nil
+}
+
+// Execute runs actions on the form that is currently open. Actions may open another form of the application.
+func (s *AppStack) Execute(ctx context.Context, formOID string, actions ...FormAction) error {
+	err := s.send(ctx, AppStackRequest{
+		Action:        appStackExecute,
+		ActionRequest: &ActionRequest{FormOID: formOID, FormActions: actions},
+	})
+	if err != nil {
+		return fmt.Errorf("error executing actions on %s: %w", formOID, err)
+	}
+	return nil
+}
+
+// Close closes the application stack, discarding the forms still open.
+func (s *AppStack) Close(ctx context.Context) error {
+	return s.send(ctx, AppStackRequest{Action: appStackClose})
+}
+
+// send issues the request on the stack, and fails if a form reports an error.
+func (s *AppStack) send(ctx context.Context, req AppStackRequest) error {
+	req.StackID = s.state.StackID
+	req.StateID = s.state.StateID
+	req.Rid = s.state.Rid
+	req.SessionFields = s.c.sessionFields()
+
+	u, err := url.JoinPath(s.c.baseUrl, appstack)
+	if err != nil {
+		return err
+	}
+
+	var res AppStackResponse
+	if req.Action == appStackOpen {
+		// nothing is open yet, the stack can be opened in a new session if the current one expired.
+		if err := s.c.doRequest(ctx, http.MethodPost, u, req, &res); err != nil {
+			return err
+		}
+	} else {
+		// the stack belongs to the session it was opened in, and is lost along with it.
+		token, err := s.c.session(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err := s.c.send(ctx, token, http.MethodPost, u, req, &res); err != nil {
+			if sessionExpired(err) {
+				return fmt.Errorf("AIS session expired while application stack %d was open: %w", req.StackID, err)
+			}
+			return err
+		}
+	}
+	if req.Action != appStackClose {
+		s.state = res
+	}
+
+	return res.formErrors()
+}
@@ -0,0 +1,125 @@
+package jde
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// AuthMode selects how requests to the AIS server are authenticated.
+type AuthMode string
+
+const (
+	// AuthModeToken opens an AIS session through tokenrequest and sends its token with every request.
+	AuthModeToken AuthMode = "token"
+	// AuthModeBasic sends the credentials with every request as HTTP Basic authentication, without an AIS session.
+	AuthModeBasic AuthMode = "basic"
+	// AuthModeOAuth sends an OAuth 2.0 access token obtained through the client credentials flow with every request.
+	AuthModeOAuth AuthMode = "oauth"
+)
+
+// ParseAuthMode returns the AuthMode named by mode, defaulting to AuthModeToken.
+func ParseAuthMode(mode string) (AuthMode, error) {
+	switch AuthMode(mode) {
+	case "", AuthModeToken:
+		return AuthModeToken, nil
+	case AuthModeBasic:
+		return AuthModeBasic, nil
+	case AuthModeOAuth:
+		return AuthModeOAuth, nil
+	default:
+		return "", fmt.Errorf("unsupported auth mode %q, expected one of %q, %q or %q", mode, AuthModeToken, AuthModeBasic, AuthModeOAuth)
+	}
+}
+
+// usesSession reports whether requests are bound to an AIS session opened through tokenrequest.
+func (m AuthMode) usesSession() bool {
+	return m == AuthModeToken
+}
+
+// OAuthCredentials configures the OAuth 2.0 client credentials flow.
+type OAuthCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// NewOAuthTokenSource returns a token source that caches the access token and fetches a new one once it expires.
+// The context is kept to fetch later tokens, so it should live as long as the client.
+// Tokens are requested with httpClient, so they go through the same TLS configuration as the AIS requests.
+func NewOAuthTokenSource(ctx context.Context, httpClient *http.Client, creds OAuthCredentials) oauth2.TokenSource {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	cfg := &clientcredentials.Config{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		TokenURL:     creds.TokenURL,
+		Scopes:       creds.Scopes,
+	}
+
+	return cfg.TokenSource(ctx)
+}
+
+// validate checks that the credentials needed by the auth mode are present.
+func (c Credentials) validate() error {
+	if c.AuthMode == AuthModeOAuth {
+		if c.OAuth.TokenURL == "" || c.OAuth.ClientID == "" || c.OAuth.ClientSecret == "" {
+			return errors.New("oauth auth mode requires a token url, client id and client secret")
+		}
+		if c.TokenSource == nil {
+			return errors.New("oauth auth mode requires a token source")
+		}
+		return nil
+	}
+
+	if c.Username == "" || c.Password == "" {
+		return fmt.Errorf("%s auth mode requires a username and password", c.AuthMode)
+	}
+
+	return nil
+}
+
+// requestAuthHeaders returns the headers that authenticate a request which isn't bound to an AIS session.
+func requestAuthHeaders(creds Credentials) (map[string]string, error) {
+	switch creds.AuthMode {
+	case AuthModeBasic:
+		auth := creds.Username + ":" + creds.Password
+		return map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))}, nil
+	case AuthModeOAuth:
+		token, err := creds.TokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("error fetching oauth access token: %w", err)
+		}
+		return map[string]string{"Authorization": token.Type() + " " + token.AccessToken}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// authHeaders returns the headers that authenticate a request, given the token of the current session.
+func (c *Client) authHeaders(token string) (map[string]string, error) {
+	if !c.creds.AuthMode.usesSession() {
+		return requestAuthHeaders(c.creds)
+	}
+
+	return map[string]string{"jde-AIS-Auth": token}, nil
+}
+
+// sessionFields returns the login details to add to requests, which are only sent when requests are not bound
+// to an AIS session.
+func (c *Client) sessionFields() SessionFields {
+	if c.creds.AuthMode.usesSession() {
+		return SessionFields{}
+	}
+
+	return SessionFields{
+		Environment: c.creds.Environment,
+		Role:        c.creds.Role,
+		DeviceName:  c.creds.DeviceName,
+	}
+}
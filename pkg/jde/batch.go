@@ -0,0 +1,146 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// BatchRequestBody bundles data requests into a single dataservice request, see Features.Batch.
+type BatchRequestBody struct {
+	Token            string            `json:"token,omitempty"`
+	BatchDataRequest bool              `json:"batchDataRequest"`
+	DataRequests     []DataRequestBody `json:"dataRequests"`
+	SessionFields
+}
+
+// batchPrefix starts the keys of the responses of a batch, "fs_<index>_DATABROWSE_<table>".
+const batchPrefix = "fs_"
+
+// NextBatch fetches the first page of every pager in a single dataservice request and hands their rows to emit,
+// along with the index of the pager they belong to. The pagers then continue on their own with Next.
+// Pagers are fetched one by one when the AIS server lacks the batch capability, or when they can't be batched:
+// they have already started, or their query has no Key to request the following pages by.
+func NextBatch[T any](ctx context.Context, c *Client, pagers []*Pager[T], emit func(i int, row T) error) error {
+	var batched []int
+	for i, p := range pagers {
+		if p.err == nil && !p.started && p.key != "" && c.features.Batch && c.features.Aggregation {
+			batched = append(batched, i)
+			continue
+		}
+
+		err := p.NextFunc(ctx, func(row T) error {
+			return emit(i, row)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	switch len(batched) {
+	case 0:
+		return nil
+	case 1:
+		i := batched[0]
+		return pagers[i].NextFunc(ctx, func(row T) error {
+			return emit(i, row)
+		})
+	}
+
+	batch := BatchRequestBody{BatchDataRequest: true}
+	readers := make([]*pageReader[T], len(batched))
+	for n, i := range batched {
+		// the rows left after the page are requested by key, batches don't hand out next links.
+		dataRequest := pagers[i].request
+		dataRequest.EnableNextPageProcessing = "false"
+		batch.DataRequests = append(batch.DataRequests, dataRequest)
+
+		i := i
+		readers[n] = &pageReader[T]{p: pagers[i], emit: func(row T) error {
+			return emit(i, row)
+		}}
+	}
+	batch.SessionFields = c.sessionFields()
+
+	reqUrl, _ := url.JoinPath(c.baseUrl, dataservice)
+	var pages []rowsPage
+	err := c.inSession(ctx, func(token string) error {
+		return c.streamBody(ctx, token, reqUrl, batch, func(body io.Reader, emitted func()) error {
+			var err error
+			pages, err = decodeBatch(body, len(readers), func(n int, table string, row Row) error {
+				emitted()
+				return readers[n].read(table, row)
+			})
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("error sending batch of %d data requests: %w", len(readers), err)
+	}
+
+	for n, r := range readers {
+		page := pages[n]
+		if page.table == "" {
+			page.table = r.p.request.TargetName
+		}
+
+		more := page.moreRecords
+		if pageSize, err := strconv.Atoi(r.p.request.MaxPageSize); err == nil {
+			more = more || page.rows >= pageSize
+		}
+		if err := r.p.advance(ctx, page, r, more); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeBatch reads the responses of a batch of size data requests like decodeStream, handing the rows to fn along
+// with the index of the request they answer.
+func decodeBatch(r io.Reader, size int, fn func(n int, table string, row Row) error) ([]rowsPage, error) {
+	dec := json.NewDecoder(r)
+	pages := make([]rowsPage, size)
+
+	err := walkObject(dec, func(key string) error {
+		n, table, ok := parseBatchKey(key)
+		if !ok {
+			return skipValue(dec)
+		}
+		if n >= size {
+			return fmt.Errorf("unexpected response %s to a batch of %d requests", key, size)
+		}
+
+		pages[n].table = table
+		return decodeResource(dec, &pages[n], func(table string, row Row) error {
+			return fn(n, table, row)
+		})
+	})
+
+	return pages, err
+}
+
+// parseBatchKey splits "fs_<index>_DATABROWSE_<table>" into the index and the table.
+func parseBatchKey(key string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(key, batchPrefix)
+	if !ok {
+		return 0, "", false
+	}
+	index, rest, ok := strings.Cut(rest, "_")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(index)
+	if err != nil || n < 0 {
+		return 0, "", false
+	}
+	table, ok := strings.CutPrefix(batchPrefix+rest, dataBrowsePrefix)
+	if !ok {
+		return 0, "", false
+	}
+	return n, table, true
+}
@@ -0,0 +1,79 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNextBatch(t *testing.T) {
+	members := map[string][]string{"R1": {"A", "B", "C"}, "R2": {"D"}, "R3": nil}
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var batch BatchRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil || !batch.BatchDataRequest {
+			t.Errorf("expected a batch request: %v", err)
+		}
+
+		var resources []string
+		for n, req := range batch.DataRequests {
+			role := req.Query.Condition[0].Value[0].Content
+			var rows []string
+			for _, u := range members[role] {
+				if len(rows) < 2 {
+					rows = append(rows, fmt.Sprintf(`{"F95921_FRROLE": %q, "F95921_TOROLE": %q}`, role, u))
+				}
+			}
+			resources = append(resources, fmt.Sprintf(`"fs_%d_DATABROWSE_F95921": {"data": {"gridData": {"rowset": [%s]}}}`, n, strings.Join(rows, ",")))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{%s}`, strings.Join(resources, ","))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.Client(), srv.URL, Credentials{AuthMode: AuthModeBasic, Username: "u", Password: "p"},
+		ClientOptions{Limits: QueryLimits{PageSize: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	features := defaultFeatures
+	features.Batch = true
+	c.UseFeatures(features)
+
+	roles := []string{"R1", "R2", "R3"}
+	var pagers []*Pager[RoleUser]
+	for _, role := range roles {
+		pagers = append(pagers, c.RoleUsers(role))
+	}
+
+	got := map[string][]string{}
+	err = NextBatch(context.Background(), c, pagers, func(i int, row RoleUser) error {
+		got[roles[i]] = append(got[roles[i]], row.User)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("expected a single request, got %d", requests.Load())
+	}
+	// B is left out of the full page of R1, as its rows may continue on the next page.
+	if strings.Join(got["R1"], ",") != "A" || strings.Join(got["R2"], ",") != "D" || len(got["R3"]) != 0 {
+		t.Errorf("got role users %v", got)
+	}
+	if pagers[0].Done() || pagers[0].Token() == "" {
+		t.Error("expected more role users for R1")
+	}
+	if !pagers[1].Done() || !pagers[2].Done() {
+		t.Error("expected R2 and R3 to be done")
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"golang.org/x/oauth2"
 )
 
 const (
@@ -16,6 +20,7 @@ const (
 	tokenrequest = "tokenrequest"
 	config       = "defaultconfig"
 	validate     = "validate"
+	logout       = "logout"
 
 	// pageSizeNoMax is used to return all records from v1 since it does not support pagination.
 	noMax = "No max"
@@ -25,43 +30,109 @@ const (
 
 type Client struct {
 	httpClient *uhttp.BaseHttpClient
+	aisUrl     string
 	baseUrl    string
-	token      string
-	version    string
+	features   Features
+	creds      Credentials
+	retry      RetryOptions
+	breaker    circuitBreaker
+	limiter    *limiter
+	pagination PaginationMode
+	limits     QueryLimits
+
+	// mtx guards token, which is opened on first use and replaced when the AIS session expires, and loggedOut,
+	// which keeps requests still in flight after Logout from opening another session.
+	mtx       sync.RWMutex
+	token     string
+	loggedOut bool
+
+	// cursors remembers how every nextUrl handed out was reached, so it can be rebuilt in a new session.
+	cursors sync.Map
+	// links maps the page tokens of keyed queries to the nextUrl of their page, while the session lives.
+	links sync.Map
+	// shortPages holds the tables a short page was already logged for.
+	shortPages sync.Map
 }
 
-func NewClient(httpClient *http.Client, aisUrl, token, env, version string) (*Client, error) {
-	path := getApiPath(version)
-	baseUrl, _ := url.JoinPath(aisUrl, path)
+// NewClient returns a client for the AIS server that sends every request through httpClient, see NewHTTPClient.
+// The AIS session is opened on the first request and released with Logout.
+// Until Negotiate is called, the client talks to the v2 API and assumes next links and aggregation are supported.
+func NewClient(httpClient *http.Client, aisUrl string, creds Credentials, opts ClientOptions) (*Client, error) {
+	if err := creds.validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.Limits.validate(); err != nil {
+		return nil, err
+	}
 
-	return &Client{
+	c := &Client{
 		httpClient: uhttp.NewBaseHttpClient(httpClient),
-		baseUrl:    baseUrl,
-		token:      token,
-		version:    version,
-	}, nil
+		aisUrl:     aisUrl,
+		creds:      creds,
+		retry:      opts.Retry,
+		limiter:    newLimiter(opts.RateLimit),
+		pagination: opts.Pagination,
+		limits:     opts.Limits,
+	}
+	c.UseFeatures(defaultFeatures)
+
+	return c, nil
+}
+
+// ClientOptions tunes how the client treats the AIS server.
+type ClientOptions struct {
+	Retry      RetryOptions
+	RateLimit  RateLimitOptions
+	Pagination PaginationMode
+	Limits     QueryLimits
+}
+
+// Credentials holds everything needed to authenticate with the AIS server.
+type Credentials struct {
+	AuthMode    AuthMode
+	Username    string
+	Password    string
+	Environment string
+	Role        string
+	DeviceName  string
+	OAuth       OAuthCredentials
+	// TokenSource provides the access tokens in AuthModeOAuth, see NewOAuthTokenSource.
+	TokenSource oauth2.TokenSource
 }
 
 type AuthRequestBody struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Environment string `json:"environment,omitempty"`
+	Role        string `json:"role,omitempty"`
+	DeviceName  string `json:"deviceName,omitempty"`
 }
 
 type DataRequestBody struct {
-	Token                    string `json:"token,omitempty"`
-	TargetName               string `json:"targetName,omitempty"`
-	TargetType               string `json:"targetType,omitempty"`
-	DataServiceType          string `json:"dataServiceType,omitempty"`
-	MaxPageSize              string `json:"maxPageSize,omitempty"`
-	ReturnControlIDs         string `json:"returnControlIDs,omitempty"`
-	EnableNextPageProcessing string `json:"enableNextPageProcessing,omitempty"`
-	FindOnEntry              string `json:"findOnEntry,omitempty"`
-	Query                    *Query `json:"query,omitempty"`
-	OutputType               string `json:"outputType,omitempty"`
+	Token                    string       `json:"token,omitempty"`
+	TargetName               string       `json:"targetName,omitempty"`
+	TargetType               string       `json:"targetType,omitempty"`
+	DataServiceType          string       `json:"dataServiceType,omitempty"`
+	MaxPageSize              string       `json:"maxPageSize,omitempty"`
+	ReturnControlIDs         string       `json:"returnControlIDs,omitempty"`
+	EnableNextPageProcessing string       `json:"enableNextPageProcessing,omitempty"`
+	FindOnEntry              string       `json:"findOnEntry,omitempty"`
+	Query                    *Query       `json:"query,omitempty"`
+	Aggregation              *Aggregation `json:"aggregation,omitempty"`
+	OutputType               string       `json:"outputType,omitempty"`
+	SessionFields
+}
+
+// SessionFields carry the login details of requests that are not bound to an AIS session, see sessionFields.
+type SessionFields struct {
+	Environment string `json:"environment,omitempty"`
+	Role        string `json:"role,omitempty"`
+	DeviceName  string `json:"deviceName,omitempty"`
 }
 
 type Query struct {
 	AutoFind  bool        `json:"autoFind"`
+	MatchType MatchType   `json:"matchType,omitempty"`
 	Condition []Condition `json:"condition"`
 }
 
@@ -82,21 +153,30 @@ type RequestParams struct {
 	Res         interface{}
 	Payload     interface{}
 	QueryParams url.Values
+	Headers     map[string]string
 }
 
 type ValidateTokenBody struct {
 	Token string `json:"token"`
 }
 
+type LogoutBody struct {
+	Token string `json:"token"`
+}
+
 // Authenticate authenticates the user with the JD Edwards EnterpriseOne AIS server and returns the token.
-func Authenticate(ctx context.Context, ais, username, password, env, version string) (string, error) {
+// When an environment or role is requested, the session AIS opened must match it.
+func (c *Client) Authenticate(ctx context.Context) (string, error) {
+	creds := c.creds
 	authBody := AuthRequestBody{
-		Username: username,
-		Password: password,
+		Username:    creds.Username,
+		Password:    creds.Password,
+		Environment: creds.Environment,
+		Role:        creds.Role,
+		DeviceName:  creds.DeviceName,
 	}
 
-	path := getApiPath(version)
-	url, err := url.JoinPath(ais, path, tokenrequest)
+	url, err := url.JoinPath(c.baseUrl, tokenrequest)
 	if err != nil {
 		return "", err
 	}
@@ -110,182 +190,139 @@ func Authenticate(ctx context.Context, ais, username, password, env, version str
 		QueryParams: nil,
 	}
 
-	err = doRequestDefaultClient(ctx, requestParams)
+	_, err = c.do(ctx, requestParams)
 	if err != nil {
 		return "", err
 	}
 
 	if res.UserInfo.Token == "" {
-		return "", fmt.Errorf("token not obtained, check if provided credentials and environment are correct")
+		return "", &AISError{StatusCode: http.StatusOK, Kind: ErrorInvalidCredentials, Message: "token not obtained, check if provided credentials and environment are correct"}
 	}
 
-	return res.UserInfo.Token, nil
-}
-
-// ListUsers returns all the users from the JD Edwards EnterpriseOne AIS server.
-func (c *Client) ListUsers(ctx context.Context, pageSize string, enableNextPage bool) ([]Columns, string, error) {
-	if c.version == "v1" && enableNextPage {
-		pageSize = noMax
-	}
-
-	dataRequest := DataRequestBody{
-		TargetName:               "F0092",
-		TargetType:               "table",
-		DataServiceType:          "BROWSE",
-		FindOnEntry:              "true",
-		ReturnControlIDs:         "F0092.USER|F0092.UGRP",
-		MaxPageSize:              pageSize,
-		EnableNextPageProcessing: fmt.Sprint(enableNextPage),
-		OutputType:               outputType,
-		Query: &Query{
-			AutoFind: true,
-			Condition: []Condition{
-				{ControlId: "F0092.UGRP", Operator: "EQUAL", Value: []Value{
-					{Content: "", SpecialValueID: "LITERAL"},
-				}},
-			}},
-	}
-
-	url, _ := url.JoinPath(c.baseUrl, dataservice)
-	var res UsersResponse
-	err := c.doRequest(ctx, http.MethodPost, url, dataRequest, &res)
-	if err != nil {
-		return nil, "", err
+	if creds.Environment != "" && !strings.EqualFold(res.Environment, creds.Environment) {
+		return "", fmt.Errorf("AIS session opened in environment %q instead of the requested %q", res.Environment, creds.Environment)
 	}
 
-	if res.Resource.Data.GridData.Summary.MoreRecords && c.version == "v2" {
-		nextUrl := res.Links[0].Href
-		return res.Resource.Data.GridData.Rowset, nextUrl, nil
+	if creds.Role != "" && !strings.EqualFold(res.Role, creds.Role) {
+		return "", fmt.Errorf("AIS session opened with role %q instead of the requested %q", res.Role, creds.Role)
 	}
 
-	return res.Resource.Data.GridData.Rowset, "", nil
+	return res.UserInfo.Token, nil
 }
 
-func (c *Client) FetchMoreUsers(ctx context.Context, nextUrl string) ([]Columns, string, error) {
-	var res UsersResponse
-	err := c.doRequest(ctx, http.MethodPost, nextUrl, nil, &res)
-	if err != nil {
-		return nil, "", err
-	}
-
-	if res.Resource.Data.GridData.Summary.MoreRecords {
-		nextUrl := res.Links[0].Href
-		return res.Resource.Data.GridData.Rowset, nextUrl, nil
-	}
-
-	return res.Resource.Data.GridData.Rowset, "", nil
+// Users pages through the users of the JD Edwards EnterpriseOne AIS server, leaving out the profiles of user groups
+// and roles.
+func (c *Client) Users() *Pager[User] {
+	return Paginate[User](c, Browse("F0092").
+		Columns("USER", "UGRP", "AN8").
+		Where("UGRP", OpNotEqual, GroupProfile).
+		Where("UGRP", OpNotEqual, RoleProfile).
+		Key("USER"),
+	)
 }
 
-// ListRoles returns all the roles from the JD Edwards EnterpriseOne AIS server.
-func (c *Client) ListRoles(ctx context.Context, pageSize string) ([]Columns, string, error) {
-	if c.version == "v1" {
-		pageSize = noMax
-	}
-
-	dataRequest := DataRequestBody{
-		TargetName:               "F00926",
-		TargetType:               "table",
-		DataServiceType:          "BROWSE",
-		FindOnEntry:              "true",
-		ReturnControlIDs:         "F00926.USER|F00926.ROLEDESC",
-		MaxPageSize:              pageSize,
-		EnableNextPageProcessing: "true",
-		OutputType:               outputType,
-	}
-
-	url, _ := url.JoinPath(c.baseUrl, dataservice)
-	var res RolesResponse
-	err := c.doRequest(ctx, http.MethodPost, url, dataRequest, &res)
+// User fetches the profile of a single user. It reports false if there is no such user.
+func (c *Client) User(ctx context.Context, userID string) (User, bool, error) {
+	dataRequest, err := Browse("F0092").Columns("USER", "UGRP", "AN8").Where("USER", OpEqual, userID).PageSize("1").NextPage(false).Build()
 	if err != nil {
-		return nil, "", err
+		return User{}, false, err
 	}
 
-	if res.Resource.Data.GridData.Summary.MoreRecords && c.version == "v2" {
-		nextUrl := res.Links[0].Href
-		return res.Resource.Data.GridData.Rowset, nextUrl, nil
-	}
-
-	return res.Resource.Data.GridData.Rowset, "", nil
-}
-
-func (c *Client) FetchMoreRoles(ctx context.Context, nextUrl string) ([]Columns, string, error) {
-	var res RolesResponse
-	err := c.doRequest(ctx, http.MethodPost, nextUrl, nil, &res)
+	users, err := NewPager[User](c, dataRequest).Next(ctx)
 	if err != nil {
-		return nil, "", err
+		return User{}, false, fmt.Errorf("error fetching user %s: %w", userID, err)
 	}
-
-	if res.Resource.Data.GridData.Summary.MoreRecords {
-		nextUrl := res.Links[0].Href
-		return res.Resource.Data.GridData.Rowset, nextUrl, nil
+	if len(users) == 0 {
+		return User{}, false, nil
 	}
 
-	return res.Resource.Data.GridData.Rowset, "", nil
+	return users[0], true, nil
 }
 
-// ListRoleUsers returns all the users that are assigned to a role from the JD Edwards EnterpriseOne AIS server.
-func (c *Client) ListRoleUsers(ctx context.Context, roleID string, pageSize string) ([]Columns, string, error) {
-	if c.version == "v1" {
-		pageSize = noMax
-	}
+// Groups pages through the user groups of the JD Edwards EnterpriseOne AIS server.
+func (c *Client) Groups() *Pager[Group] {
+	return Paginate[Group](c, Browse("F0092").
+		Columns("USER").
+		Where("UGRP", OpEqual, GroupProfile).
+		Key("USER"),
+	)
+}
 
-	dataRequest := DataRequestBody{
-		TargetName:               "F95921",
-		TargetType:               "table",
-		DataServiceType:          "BROWSE",
-		FindOnEntry:              "true",
-		ReturnControlIDs:         "F95921.FRROLE|F95921.TOROLE",
-		MaxPageSize:              pageSize,
-		EnableNextPageProcessing: "true",
-		OutputType:               outputType,
-		Query: &Query{
-			AutoFind: true,
-			Condition: []Condition{
-				{ControlId: "F95921.FRROLE", Operator: "EQUAL", Value: []Value{
-					{Content: roleID, SpecialValueID: "LITERAL"},
-				}},
-			}},
-	}
+// GroupMembers pages through the users whose user group is groupID.
+func (c *Client) GroupMembers(groupID string) *Pager[User] {
+	return Paginate[User](c, Browse("F0092").
+		Columns("USER", "UGRP", "AN8").
+		Where("UGRP", OpEqual, groupID).
+		Key("USER"),
+	)
+}
 
-	url, _ := url.JoinPath(c.baseUrl, dataservice)
-	var res RoleUsersResponse
-	err := c.doRequest(ctx, http.MethodPost, url, dataRequest, &res)
-	if err != nil {
-		return nil, "", err
-	}
+// AddressBook pages through the address book entries with the given address numbers.
+func (c *Client) AddressBook(addressNumbers ...int64) *Pager[AddressBookEntry] {
+	return Paginate[AddressBookEntry](c, Browse("F0101").
+		Columns("AN8", "ALPH", "AT1").
+		Where("AN8", OpList, formatInts(addressNumbers)...).
+		Key("AN8"),
+	)
+}
 
-	if res.Resource.Data.GridData.Summary.MoreRecords && c.version == "v2" {
-		nextUrl := res.Links[0].Href
-		return res.Resource.Data.GridData.Rowset, nextUrl, nil
-	}
+// EmailAddresses pages through the email addresses of the address book entries with the given address numbers.
+func (c *Client) EmailAddresses(addressNumbers ...int64) *Pager[ElectronicAddress] {
+	return Paginate[ElectronicAddress](c, Browse("F01151").
+		Columns("AN8", "IDLN", "RCK7", "ETP", "EMAL").
+		Where("AN8", OpList, formatInts(addressNumbers)...).
+		Where("ETP", OpEqual, ElectronicAddressEmail),
+	)
+}
 
-	return res.Resource.Data.GridData.Rowset, "", nil
+// SignOnSecurity pages through the sign-on security of the given users.
+func (c *Client) SignOnSecurity(userIDs ...string) *Pager[SignOnSecurity] {
+	return Paginate[SignOnSecurity](c, Browse("F98OWSEC").
+		Columns("USER", "EUSER", "SECFRQ", "SECLST", "ATTEMPTS", "SECINV").
+		Where("USER", OpList, userIDs...).
+		Key("USER"),
+	)
 }
 
-func (c *Client) FetchMoreRoleUsers(ctx context.Context, nextUrl string) ([]Columns, string, error) {
-	var res RoleUsersResponse
-	err := c.doRequest(ctx, http.MethodPost, nextUrl, nil, &res)
-	if err != nil {
-		return nil, "", err
-	}
+// Roles pages through the roles of the JD Edwards EnterpriseOne AIS server.
+func (c *Client) Roles() *Pager[Role] {
+	return Paginate[Role](c, Browse("F00926").
+		Columns("USER", "ROLEDESC").
+		Key("USER"),
+	)
+}
 
-	if res.Resource.Data.GridData.Summary.MoreRecords {
-		nextUrl := res.Links[0].Href
-		return res.Resource.Data.GridData.Rowset, nextUrl, nil
-	}
+// RoleUsers pages through the users that are assigned to a role on the JD Edwards EnterpriseOne AIS server.
+func (c *Client) RoleUsers(roleID string) *Pager[RoleUser] {
+	return Paginate[RoleUser](c, Browse("F95921").
+		Columns("FRROLE", "TOROLE", "EFFFROM", "EFFTHRU", "FUSE", "SEQN").
+		Where("FRROLE", OpEqual, roleID).
+		// users are assigned a role once per effective date.
+		Key("TOROLE", "EFFFROM"),
+	)
+}
 
-	return res.Resource.Data.GridData.Rowset, "", nil
+// RoleRelationships pages through every role assignment of the JD Edwards EnterpriseOne AIS server, in no
+// particular order.
+func (c *Client) RoleRelationships() *Pager[RoleUser] {
+	return Paginate[RoleUser](c, Browse("F95921").Columns("FRROLE", "TOROLE", "EFFFROM", "EFFTHRU", "FUSE", "SEQN"))
 }
 
 // ValidateToken validates the current session token.
 func (c *Client) ValidateToken(ctx context.Context) (ValidateTokenResponse, error) {
+	token, err := c.session(ctx)
+	if err != nil {
+		return ValidateTokenResponse{}, err
+	}
+
 	url, _ := url.JoinPath(c.baseUrl, tokenrequest, validate)
 	body := ValidateTokenBody{
-		Token: c.token,
+		Token: token,
 	}
 
+	// validating must report on the current session, so don't re-authenticate here.
 	var res ValidateTokenResponse
-	err := c.doRequest(ctx, http.MethodPost, url, body, &res)
+	_, err = c.send(ctx, token, http.MethodPost, url, body, &res)
 	if err != nil {
 		return ValidateTokenResponse{}, err
 	}
@@ -293,12 +330,17 @@ func (c *Client) ValidateToken(ctx context.Context) (ValidateTokenResponse, erro
 	return res, nil
 }
 
-func GetConfigv1(ctx context.Context, ais string) (ConfigResponse, bool, error) {
+func (c *Client) GetConfigv1(ctx context.Context) (ConfigResponse, bool, error) {
 	params := url.Values{}
 	params.Add("requiredCapabilities", "dataservice,outputType")
 	var res ConfigResponse
 
-	url, err := url.JoinPath(ais, apiPathv1, config)
+	url, err := url.JoinPath(c.aisUrl, apiPathv1, config)
+	if err != nil {
+		return ConfigResponse{}, false, err
+	}
+
+	headers, err := requestAuthHeaders(c.creds)
 	if err != nil {
 		return ConfigResponse{}, false, err
 	}
@@ -309,24 +351,30 @@ func GetConfigv1(ctx context.Context, ais string) (ConfigResponse, bool, error)
 		Res:         &res,
 		Payload:     nil,
 		QueryParams: params,
+		Headers:     headers,
 	}
-	if err := doRequestDefaultClient(ctx, requestParams); err != nil {
+	if _, err := c.do(ctx, requestParams); err != nil {
 		return ConfigResponse{}, false, err
 	}
 
 	if res.RequiredCapabilityMissing {
-		return ConfigResponse{}, true, nil
+		return res, true, nil
 	}
 
 	return res, false, nil
 }
 
-func GetConfigv2(ctx context.Context, ais string) (ConfigResponse, bool, error) {
+func (c *Client) GetConfigv2(ctx context.Context) (ConfigResponse, bool, error) {
 	params := url.Values{}
 	params.Add("all", "true")
 	params.Add("requiredCapabilities", "dataservice,outputType")
 
-	url, err := url.JoinPath(ais, apiPathv2, config)
+	url, err := url.JoinPath(c.aisUrl, apiPathv2, config)
+	if err != nil {
+		return ConfigResponse{}, false, err
+	}
+
+	headers, err := requestAuthHeaders(c.creds)
 	if err != nil {
 		return ConfigResponse{}, false, err
 	}
@@ -338,28 +386,31 @@ func GetConfigv2(ctx context.Context, ais string) (ConfigResponse, bool, error)
 		Res:         &res,
 		Payload:     nil,
 		QueryParams: params,
+		Headers:     headers,
 	}
-	if err := doRequestDefaultClient(ctx, requestParams); err != nil {
+	if _, err := c.do(ctx, requestParams); err != nil {
 		return ConfigResponse{}, false, err
 	}
 
 	if res.RequiredCapabilityMissing {
-		return ConfigResponse{}, true, nil
+		return res, true, nil
 	}
 
 	return res, false, nil
 }
 
-func GetConfig(ctx context.Context, ais string) (ConfigResponse, bool, string, error) {
+// GetConfig fetches the AIS server configuration and reports which API version answered. When requests aren't bound
+// to an AIS session, the credentials are sent along in case the server doesn't allow anonymous access to it.
+func (c *Client) GetConfig(ctx context.Context) (ConfigResponse, bool, string, error) {
 	var config ConfigResponse
 	var capabilityMissing bool
 	var err error
 	version := "v2"
 
 	// try to fetch config from v2, if it fails, try v1
-	config, capabilityMissing, err = GetConfigv2(ctx, ais)
+	config, capabilityMissing, err = c.GetConfigv2(ctx)
 	if err != nil {
-		config, capabilityMissing, err = GetConfigv1(ctx, ais)
+		config, capabilityMissing, err = c.GetConfigv1(ctx)
 		if err != nil {
 			return ConfigResponse{}, capabilityMissing, "", err
 		}
@@ -369,9 +420,14 @@ func GetConfig(ctx context.Context, ais string) (ConfigResponse, bool, string, e
 	return config, capabilityMissing, version, nil
 }
 
-// ValidateTokenV1 validates the current session token.
+// ValidateTokenV1 validates the current session token, or the credentials when no session is used, by fetching a user.
 func (c *Client) ValidateTokenV1(ctx context.Context) error {
-	_, _, err := c.ListUsers(ctx, "1", false)
+	dataRequest, err := Browse("F0092").Columns("USER").PageSize("1").NextPage(false).Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = NewPager[User](c, dataRequest).Next(ctx)
 	if err != nil {
 		return fmt.Errorf("error validating token while fetching user: %w", err)
 	}
@@ -379,71 +435,98 @@ func (c *Client) ValidateTokenV1(ctx context.Context) error {
 	return nil
 }
 
+// doRequest sends the request and, if AIS rejects it because the session expired, re-authenticates and sends it once more.
 func (c *Client) doRequest(ctx context.Context, method string, reqUrl string, payload interface{}, res interface{}) error {
-	u, err := url.Parse(reqUrl)
-	if err != nil {
+	return c.inSession(ctx, func(token string) error {
+		_, err := c.send(ctx, token, method, reqUrl, payload, res)
 		return err
-	}
+	})
+}
 
-	req, err := c.httpClient.NewRequest(
-		ctx,
-		method,
-		u,
-		uhttp.WithJSONBody(payload),
-		uhttp.WithAcceptJSONHeader(),
-		uhttp.WithContentTypeJSONHeader(),
-		uhttp.WithHeader("jde-AIS-Auth", c.token),
-	)
+// send issues a single request within the session of the given token. A nil res means the response body is ignored.
+func (c *Client) send(ctx context.Context, token string, method string, reqUrl string, payload interface{}, res interface{}) (*http.Response, error) {
+	headers, err := c.authHeaders(token)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req, uhttp.WithJSONResponse(res))
+	return c.do(ctx, RequestParams{
+		Url:     reqUrl,
+		Method:  method,
+		Res:     res,
+		Payload: payload,
+		Headers: headers,
+	})
+}
+
+// do issues a request to the AIS server. Every request, authenticated or not, goes through here, except for the
+// dataservice requests streamed with stream.
+// Idempotent requests are retried when the AIS server fails, see RetryOptions.
+func (c *Client) do(ctx context.Context, requestParams RequestParams) (*http.Response, error) {
+	u, err := url.Parse(requestParams.Url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp.Body.Close()
+	var doOptions []uhttp.DoOption
+	if requestParams.Res != nil {
+		doOptions = append(doOptions, uhttp.WithJSONResponse(requestParams.Res))
+	}
 
-	return nil
-}
+	var resp *http.Response
+	err = c.withRetry(ctx, idempotent(requestParams.Payload), func() error {
+		release, err := c.limiter.wait(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
 
-func doRequestDefaultClient(ctx context.Context, requestParams RequestParams) error {
-	httpClient, err := (&uhttp.NoAuth{}).GetClient(ctx)
-	if err != nil {
+		req, err := c.newRequest(ctx, requestParams)
+		if err != nil {
+			return err
+		}
+
+		resp, err = c.httpClient.Do(req, doOptions...)
+		if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+			return newAISError(resp, strings.HasSuffix(u.Path, "/"+tokenrequest))
+		}
 		return err
+	})
+	if err != nil {
+		return resp, err
 	}
 
-	client := uhttp.NewBaseHttpClient(httpClient)
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// newRequest builds a JSON request to the AIS server.
+func (c *Client) newRequest(ctx context.Context, requestParams RequestParams) (*http.Request, error) {
 	u, err := url.Parse(requestParams.Url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := client.NewRequest(
-		ctx,
-		requestParams.Method,
-		u,
+	reqOptions := []uhttp.RequestOption{
 		uhttp.WithJSONBody(requestParams.Payload),
 		uhttp.WithAcceptJSONHeader(),
 		uhttp.WithContentTypeJSONHeader(),
-	)
-	if err != nil {
-		return err
 	}
-
-	if requestParams.QueryParams != nil {
-		req.URL.RawQuery = requestParams.QueryParams.Encode()
+	for k, v := range requestParams.Headers {
+		reqOptions = append(reqOptions, uhttp.WithHeader(k, v))
 	}
 
-	resp, err := client.Do(req, uhttp.WithJSONResponse(requestParams.Res))
+	req, err := c.httpClient.NewRequest(ctx, requestParams.Method, u, reqOptions...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp.Body.Close()
+	if requestParams.QueryParams != nil {
+		req.URL.RawQuery = requestParams.QueryParams.Encode()
+	}
 
-	return nil
+	return req, nil
 }
 
 func getApiPath(version string) string {
@@ -454,11 +537,10 @@ func getApiPath(version string) string {
 	return path
 }
 
-func CanValidateToken(ctx context.Context, config ConfigResponse) bool {
-	for _, capability := range config.CapabilityList {
-		if capability.Name == validate {
-			return true
-		}
+func formatInts(values []int64) []string {
+	rv := make([]string, 0, len(values))
+	for _, v := range values {
+		rv = append(rv, strconv.FormatInt(v, 10))
 	}
-	return false
+	return rv
 }
@@ -0,0 +1,99 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUsersLeaveOutProfiles(t *testing.T) {
+	profiles := [][2]string{{"ADMINS", GroupProfile}, {"ALICE", ""}, {"BOB", "ADMINS"}, {"SYSADMIN", RoleProfile}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req DataRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("error decoding request: %v", err)
+		}
+
+		var rows []string
+		for _, p := range profiles {
+			match := true
+			for _, c := range req.Query.Condition {
+				if c.ControlId == "F0092.UGRP" && c.Operator == string(OpNotEqual) && p[1] == c.Value[0].Content {
+					match = false
+				}
+			}
+			if match {
+				rows = append(rows, fmt.Sprintf(`{"F0092_USER": %q, "F0092_UGRP": %q}`, p[0], p[1]))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"fs_DATABROWSE_F0092": {"data": {"gridData": {"rowset": [%s]}}}}`, strings.Join(rows, ","))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.Client(), srv.URL, Credentials{AuthMode: AuthModeBasic, Username: "u", Password: "p"}, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := c.Users().Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	if strings.Join(ids, ",") != "ALICE,BOB" {
+		t.Errorf("got users %v, want ALICE,BOB", ids)
+	}
+}
+
+func TestAuthenticateChecksSession(t *testing.T) {
+	// AIS falls back to the default environment and role of the user when the requested ones aren't allowed.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"environment": "JDV920", "role": "*ALL", "userInfo": {"token": "t1"}}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		environment string
+		role        string
+		err         string
+	}{
+		{"defaults", "", "", ""},
+		{"same environment and role", "jdv920", "*all", ""},
+		{"other environment", "JPY920", "", `environment "JDV920" instead of the requested "JPY920"`},
+		{"other role", "JDV920", "SYSADMIN", `role "*ALL" instead of the requested "SYSADMIN"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds := Credentials{AuthMode: AuthModeToken, Username: "u", Password: "p", Environment: tt.environment, Role: tt.role}
+			c, err := NewClient(srv.Client(), srv.URL, creds, ClientOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := c.Authenticate(context.Background())
+			if tt.err == "" {
+				if err != nil || token != "t1" {
+					t.Errorf("got token %q and error %v", token, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package jde
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxErrorBody bounds how much of a non JSON error body ends up in the error message.
+const maxErrorBody = 512
+
+// ErrorKind classifies why AIS rejected a request.
+type ErrorKind int
+
+const (
+	ErrorUnknown ErrorKind = iota
+	// ErrorInvalidCredentials means the username, password, environment or role were refused.
+	ErrorInvalidCredentials
+	// ErrorSessionExpired means the token of the AIS session expired or was logged out.
+	ErrorSessionExpired
+	// ErrorTableSecurity means the user isn't allowed to browse the table, through table or data browser security.
+	ErrorTableSecurity
+	// ErrorMissingCapability means the AIS server doesn't provide a capability the request needs.
+	ErrorMissingCapability
+	// ErrorServer means the AIS server, or the JAS/HTML server behind it, failed to process the request.
+	ErrorServer
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorInvalidCredentials:
+		return "invalid credentials"
+	case ErrorSessionExpired:
+		return "session expired"
+	case ErrorTableSecurity:
+		return "table security"
+	case ErrorMissingCapability:
+		return "missing capability"
+	case ErrorServer:
+		return "server error"
+	default:
+		return "unknown"
+	}
+}
+
+// AISError is the error payload AIS returns when it rejects a request.
+// It carries a gRPC status, so baton only retries the errors that are worth retrying.
+type AISError struct {
+	StatusCode int       `json:"-"`
+	Kind       ErrorKind `json:"-"`
+	Status     string    `json:"status,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Exception  string    `json:"exception,omitempty"`
+	TimeStamp  string    `json:"timeStamp,omitempty"`
+}
+
+func (e *AISError) Error() string {
+	msg := fmt.Sprintf("AIS request failed with status %d (%s)", e.StatusCode, e.Kind)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Exception != "" {
+		msg += " [" + e.Exception + "]"
+	}
+	return msg
+}
+
+// GRPCStatus maps the error to the gRPC code baton acts on.
+func (e *AISError) GRPCStatus() *status.Status {
+	return status.New(e.code(), e.Error())
+}
+
+func (e *AISError) code() codes.Code {
+	switch e.Kind {
+	case ErrorInvalidCredentials, ErrorSessionExpired:
+		return codes.Unauthenticated
+	case ErrorTableSecurity:
+		return codes.PermissionDenied
+	case ErrorMissingCapability:
+		return codes.FailedPrecondition
+	case ErrorServer:
+		return codes.Unavailable
+	case ErrorUnknown:
+	}
+
+	switch e.StatusCode {
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusTooManyRequests:
+		return codes.Unavailable
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	default:
+		return codes.Unknown
+	}
+}
+
+// newAISError parses the error body of a rejected request. tokenRequest tells whether the request was a login.
+func newAISError(resp *http.Response, tokenRequest bool) *AISError {
+	aisErr := &AISError{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if json.Unmarshal(body, aisErr) != nil || (aisErr.Message == "" && aisErr.Exception == "") {
+			aisErr.Message = truncate(strings.TrimSpace(string(body)), maxErrorBody)
+		}
+	}
+
+	aisErr.Kind = classify(aisErr, tokenRequest)
+	return aisErr
+}
+
+func classify(e *AISError, tokenRequest bool) ErrorKind {
+	msg := strings.ToLower(e.Message + " " + e.Exception)
+
+	switch {
+	case e.StatusCode == statusInvalidToken:
+		return ErrorSessionExpired
+	case (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden) && tokenRequest:
+		return ErrorInvalidCredentials
+	case containsAny(msg, "invalid token", "token expired", "invalid session", "session expired"):
+		return ErrorSessionExpired
+	case containsAny(msg, "capability"):
+		return ErrorMissingCapability
+	case containsAny(msg, "data browser", "table security", "not authorized to view", "access to table"):
+		return ErrorTableSecurity
+	case containsAny(msg, "invalid username", "invalid password", "authorization failure", "invalid credentials"):
+		return ErrorInvalidCredentials
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrorServer
+	default:
+		return ErrorUnknown
+	}
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,100 @@
+package jde
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          AISError
+		tokenRequest bool
+		want         ErrorKind
+	}{
+		{"invalid token status", AISError{StatusCode: statusInvalidToken}, false, ErrorSessionExpired},
+		{"unauthorized login", AISError{StatusCode: http.StatusUnauthorized}, true, ErrorInvalidCredentials},
+		{"forbidden login", AISError{StatusCode: http.StatusForbidden}, true, ErrorInvalidCredentials},
+		{"unauthorized request", AISError{StatusCode: http.StatusUnauthorized}, false, ErrorUnknown},
+		{"expired token message", AISError{StatusCode: http.StatusForbidden, Message: "Invalid Token"}, false, ErrorSessionExpired},
+		{"expired session exception", AISError{StatusCode: http.StatusInternalServerError, Exception: "Session Expired"}, false, ErrorSessionExpired},
+		{"missing capability", AISError{StatusCode: http.StatusBadRequest, Message: "The dataServiceBatch capability is not available"}, false, ErrorMissingCapability},
+		{"table security", AISError{StatusCode: http.StatusForbidden, Message: "User is not authorized to view table F0092"}, false, ErrorTableSecurity},
+		{"data browser security", AISError{StatusCode: http.StatusInternalServerError, Message: "Data Browser security"}, false, ErrorTableSecurity},
+		{"invalid password", AISError{StatusCode: http.StatusForbidden, Message: "Invalid username or password"}, false, ErrorInvalidCredentials},
+		{"server error", AISError{StatusCode: http.StatusBadGateway, Message: "JAS server unavailable"}, false, ErrorServer},
+		{"client error", AISError{StatusCode: http.StatusBadRequest, Message: "bad query"}, false, ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(&tt.err, tt.tokenRequest); got != tt.want {
+				t.Errorf("classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAISErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  AISError
+		want codes.Code
+	}{
+		{"invalid credentials", AISError{StatusCode: http.StatusUnauthorized, Kind: ErrorInvalidCredentials}, codes.Unauthenticated},
+		{"session expired", AISError{StatusCode: statusInvalidToken, Kind: ErrorSessionExpired}, codes.Unauthenticated},
+		{"table security", AISError{StatusCode: http.StatusForbidden, Kind: ErrorTableSecurity}, codes.PermissionDenied},
+		{"missing capability", AISError{StatusCode: http.StatusBadRequest, Kind: ErrorMissingCapability}, codes.FailedPrecondition},
+		{"server error", AISError{StatusCode: http.StatusInternalServerError, Kind: ErrorServer}, codes.Unavailable},
+		{"request timeout", AISError{StatusCode: http.StatusRequestTimeout}, codes.DeadlineExceeded},
+		{"too many requests", AISError{StatusCode: http.StatusTooManyRequests}, codes.Unavailable},
+		{"not found", AISError{StatusCode: http.StatusNotFound}, codes.NotFound},
+		{"unauthorized", AISError{StatusCode: http.StatusUnauthorized}, codes.Unauthenticated},
+		{"forbidden", AISError{StatusCode: http.StatusForbidden}, codes.PermissionDenied},
+		{"not implemented", AISError{StatusCode: http.StatusNotImplemented}, codes.Unimplemented},
+		{"bad request", AISError{StatusCode: http.StatusBadRequest}, codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(&tt.err); got != tt.want {
+				t.Errorf("status.Code() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAISError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantKind ErrorKind
+		wantMsg  string
+	}{
+		{"json body", http.StatusForbidden, `{"message": "Invalid Token", "exception": "JDERestServiceException"}`, ErrorSessionExpired, "Invalid Token"},
+		{"html body", http.StatusBadGateway, "<html>Proxy Error</html>", ErrorServer, "<html>Proxy Error</html>"},
+		{"long body", http.StatusBadRequest, strings.Repeat("x", maxErrorBody+10), ErrorUnknown, strings.Repeat("x", maxErrorBody) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			err := newAISError(resp, false)
+			if err.Kind != tt.wantKind || err.Message != tt.wantMsg {
+				t.Errorf("got kind %s and message %q, want %s and %q", err.Kind, err.Message, tt.wantKind, tt.wantMsg)
+			}
+
+			// the body can still be read by the caller.
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.body {
+				t.Errorf("got body %q after parsing the error", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package jde
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Names of the capabilities AIS lists in its default config.
+const (
+	capabilityValidate       = "validate"
+	capabilityAggregation    = "dataServiceAggregation"
+	capabilityBatch          = "dataServiceBatch"
+	capabilityOrchestrations = "orchestrator"
+)
+
+// Features describes what the AIS server supports, as negotiated from its default config.
+type Features struct {
+	// Version is the AIS API version requests are sent to, "v1" or "v2".
+	Version string
+	// AISVersion is the release of the AIS server, as reported in its default config.
+	AISVersion string
+	// MissingRequired is set when the AIS server lacks the dataservice or outputType capability.
+	MissingRequired bool
+
+	// ValidateToken tells whether tokens can be checked with tokenrequest/validate.
+	ValidateToken bool
+	// NextPage tells whether AIS hands out next links for the rows left out of a page, which the v2 API always does.
+	NextPage bool
+	// Aggregation tells whether data requests can order their rows.
+	Aggregation bool
+	// Batch tells whether several data requests can be sent at once.
+	Batch bool
+	// Orchestrations tells whether orchestrations can be run.
+	Orchestrations bool
+
+	capabilities map[string]bool
+}
+
+// defaultFeatures are assumed until the features of the AIS server are negotiated.
+var defaultFeatures = Features{
+	Version:     "v2",
+	NextPage:    true,
+	Aggregation: true,
+}
+
+// NewFeatures parses the capability list of the AIS default config, fetched from the given API version.
+// Capabilities only enable what the client does on top of the API version: next links are followed on the v2 API
+// whether or not the capability is listed.
+func NewFeatures(version string, config ConfigResponse) Features {
+	f := Features{
+		Version:      version,
+		AISVersion:   config.AisVersion,
+		capabilities: make(map[string]bool, len(config.CapabilityList)),
+	}
+	for _, capability := range config.CapabilityList {
+		f.capabilities[capability.Name] = true
+	}
+
+	f.ValidateToken = f.Has(capabilityValidate)
+	f.NextPage = version == "v2"
+	f.Aggregation = f.Has(capabilityAggregation)
+	f.Batch = f.Has(capabilityBatch)
+	f.Orchestrations = f.Has(capabilityOrchestrations)
+
+	return f
+}
+
+// Has reports whether the AIS server lists the named capability.
+func (f Features) Has(capability string) bool {
+	return f.capabilities[capability]
+}
+
+// Negotiate fetches the default config of the AIS server once and adapts the client to what it supports: the API
+// version, the capabilities and the page size limits. It must be called before the client is used concurrently.
+func (c *Client) Negotiate(ctx context.Context) (Features, error) {
+	config, missing, version, err := c.GetConfig(ctx)
+	if err != nil {
+		return Features{}, fmt.Errorf("error fetching config: %w", err)
+	}
+
+	features := NewFeatures(version, config)
+	features.MissingRequired = missing
+	c.UseFeatures(features)
+	c.ApplyServerLimits(ctx, config)
+
+	ctxzap.Extract(ctx).Info("baton-jd-edwards: negotiated AIS features",
+		zap.String("api_version", features.Version),
+		zap.String("ais_version", features.AISVersion),
+		zap.Bool("validate_token", features.ValidateToken),
+		zap.Bool("next_page", features.NextPage),
+		zap.Bool("aggregation", features.Aggregation),
+		zap.Bool("batch", features.Batch),
+		zap.Bool("orchestrations", features.Orchestrations),
+	)
+
+	return features, nil
+}
+
+// UseFeatures selects the API version and the capabilities requests are sent with.
+// It must be called before the client is used concurrently.
+func (c *Client) UseFeatures(features Features) {
+	c.features = features
+	c.baseUrl, _ = url.JoinPath(c.aisUrl, getApiPath(features.Version))
+}
+
+// Features returns what the AIS server supports, see Negotiate.
+func (c *Client) Features() Features {
+	return c.features
+}
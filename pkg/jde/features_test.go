@@ -0,0 +1,36 @@
+package jde
+
+import (
+	"testing"
+)
+
+func TestNewFeatures(t *testing.T) {
+	config := ConfigResponse{
+		AisVersion: "9.2.6.2",
+		CapabilityList: []CapabilityList{
+			{Name: "dataservice"},
+			{Name: capabilityValidate},
+			{Name: capabilityAggregation},
+		},
+	}
+
+	v2 := NewFeatures("v2", config)
+	if !v2.ValidateToken || !v2.NextPage || !v2.Aggregation || v2.Batch || !v2.Has("dataservice") {
+		t.Errorf("unexpected v2 features %+v", v2)
+	}
+
+	// v2 hands out next links without the capability being listed, v1 never does.
+	v1 := NewFeatures("v1", config)
+	if v1.NextPage || !v1.Aggregation {
+		t.Errorf("unexpected v1 features %+v", v1)
+	}
+
+	c := &Client{features: v1}
+	if c.paginationMode() != PaginationKeyset {
+		t.Errorf("expected keyset pagination without next links, got %q", c.paginationMode())
+	}
+	c.features = NewFeatures("v2", ConfigResponse{})
+	if c.paginationMode() != PaginationLinks {
+		t.Errorf("expected link pagination on v2, got %q", c.paginationMode())
+	}
+}
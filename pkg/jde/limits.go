@@ -0,0 +1,76 @@
+package jde
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const defaultPageSize = 100
+
+// QueryLimits bounds the rows requested from the AIS server.
+type QueryLimits struct {
+	// PageSize is the number of rows requested at once. Defaults to 100.
+	PageSize int
+	// RecordLimit caps the rows returned by a single query, the rows after it are skipped. Zero means no limit.
+	RecordLimit int
+}
+
+func (l QueryLimits) validate() error {
+	if l.PageSize < 0 {
+		return fmt.Errorf("page size must be positive, got %d", l.PageSize)
+	}
+	if l.RecordLimit < 0 {
+		return fmt.Errorf("record limit must be positive, got %d", l.RecordLimit)
+	}
+	return nil
+}
+
+// pageSize returns the page size as AIS expects it.
+func (l QueryLimits) pageSize() string {
+	if l.PageSize == 0 {
+		return strconv.Itoa(defaultPageSize)
+	}
+	return strconv.Itoa(l.PageSize)
+}
+
+// ApplyServerLimits lowers the page size to the maximum the AIS server reports in its default config, if any.
+// It must be called before the client is used concurrently.
+func (c *Client) ApplyServerLimits(ctx context.Context, config ConfigResponse) {
+	l := ctxzap.Extract(ctx)
+
+	pageSize := c.limits.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	if config.MaxPageSize > 0 && pageSize > config.MaxPageSize {
+		l.Warn("baton-jd-edwards: page size exceeds the maximum of the AIS server, using the maximum",
+			zap.Int("page_size", pageSize),
+			zap.Int("max_page_size", config.MaxPageSize),
+		)
+		c.limits.PageSize = config.MaxPageSize
+		pageSize = config.MaxPageSize
+	}
+
+	if c.limits.RecordLimit > 0 && c.limits.RecordLimit < pageSize {
+		c.limits.PageSize = c.limits.RecordLimit
+	}
+}
+
+// warnShortPage logs, once per table, that AIS returned fewer rows than requested although more rows are left.
+// That happens when the AIS administrator capped maxPageSize below the configured page size.
+func (c *Client) warnShortPage(ctx context.Context, table string, requested int, returned int) {
+	if _, warned := c.shortPages.LoadOrStore(table, struct{}{}); warned {
+		return
+	}
+
+	ctxzap.Extract(ctx).Warn("baton-jd-edwards: AIS returned fewer rows than requested, the page size is likely capped on the AIS server",
+		zap.String("table", table),
+		zap.Int("requested", requested),
+		zap.Int("returned", returned),
+	)
+}
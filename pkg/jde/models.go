@@ -1,5 +1,7 @@
 package jde
 
+import "time"
+
 type AuthResponse struct {
 	Username       string   `json:"username"`
 	Environment    string   `json:"environment"`
@@ -26,18 +28,10 @@ type Data struct {
 }
 
 type GridData struct {
-	Columns Columns   `json:"columns"`
-	Rowset  []Columns `json:"rowset"`
-	Summary Summary   `json:"summary"`
-}
-
-type Columns struct {
-	F0092User      string `json:"F0092_USER,omitempty"`
-	F0092Ugrp      string `json:"F0092_UGRP"`
-	F95921FrRole   string `json:"F95921_FRROLE,omitempty"`
-	F95921ToRole   string `json:"F95921_TOROLE,omitempty"`
-	F00926User     string `json:"F00926_USER,omitempty"`
-	F00926RoleDesc string `json:"F00926_ROLEDESC,omitempty"`
+	// Columns maps the column ids of the rowset to their titles.
+	Columns map[string]string `json:"columns"`
+	Rowset  []Row             `json:"rowset"`
+	Summary Summary           `json:"summary"`
 }
 
 type Summary struct {
@@ -45,19 +39,102 @@ type Summary struct {
 	MoreRecords bool `json:"moreRecords"`
 }
 
-type UsersResponse struct {
-	Resource Resource `json:"fs_DATABROWSE_F0092"`
-	Links    []Link   `json:"links,omitempty"`
+// User is a row of F0092, the user profiles. AddressNumber links the user to its address book entry.
+type User struct {
+	ID            string `jde:"USER"`
+	Group         string `jde:"UGRP"`
+	AddressNumber int64  `jde:"AN8"`
+}
+
+// Group is a row of F0092 that holds the profile of a user group rather than of a user.
+type Group struct {
+	ID string `jde:"USER"`
+}
+
+// GroupProfile and RoleProfile are the UGRP of the F0092 rows of user groups and of roles.
+const (
+	GroupProfile = "*GROUP"
+	RoleProfile  = "*ROLE"
+)
+
+// AddressBookEntry is a row of F0101, the address book. AlphaName usually reads "Last, First".
+type AddressBookEntry struct {
+	AddressNumber int64  `jde:"AN8"`
+	AlphaName     string `jde:"ALPH"`
+	SearchType    string `jde:"AT1"`
+}
+
+// ElectronicAddress is a row of F01151, the electronic addresses of the who's who lines of an address book entry.
+// Line 0 of the who's who is the entry itself.
+type ElectronicAddress struct {
+	AddressNumber int64  `jde:"AN8"`
+	WhosWhoLine   int64  `jde:"IDLN"`
+	Line          int64  `jde:"RCK7"`
+	Type          string `jde:"ETP"`
+	Address       string `jde:"EMAL"`
+}
+
+// ElectronicAddressEmail is the ETP of email addresses.
+const ElectronicAddressEmail = "E"
+
+// SignOnSecurity is a row of F98OWSEC, the sign-on security of a user.
+type SignOnSecurity struct {
+	User string `jde:"USER"`
+	// Status is SignOnEnabled or SignOnDisabled.
+	Status string `jde:"EUSER"`
+	// PasswordChangeFrequency is the number of days a password is valid for, 0 when it doesn't expire.
+	PasswordChangeFrequency int64     `jde:"SECFRQ"`
+	PasswordLastChanged     time.Time `jde:"SECLST"`
+	// AllowedAttempts is the number of invalid sign-ons after which the user is locked out, 0 when unlimited.
+	AllowedAttempts int64 `jde:"ATTEMPTS"`
+	InvalidAttempts int64 `jde:"SECINV"`
+}
+
+// EUSER values of F98OWSEC.
+const (
+	SignOnEnabled  = "01"
+	SignOnDisabled = "02"
+)
+
+// LockedOut reports whether the user exceeded the invalid sign-on attempts allowed.
+func (s SignOnSecurity) LockedOut() bool {
+	return s.AllowedAttempts > 0 && s.InvalidAttempts >= s.AllowedAttempts
+}
+
+// PasswordExpires returns when the password expires, or the zero time if it doesn't.
+func (s SignOnSecurity) PasswordExpires() time.Time {
+	if s.PasswordChangeFrequency <= 0 || s.PasswordLastChanged.IsZero() {
+		return time.Time{}
+	}
+	return s.PasswordLastChanged.AddDate(0, 0, int(s.PasswordChangeFrequency))
+}
+
+// Role is a row of F00926, the role descriptions.
+type Role struct {
+	ID          string `jde:"USER"`
+	Description string `jde:"ROLEDESC"`
 }
 
-type RolesResponse struct {
-	Resource Resource `json:"fs_DATABROWSE_F00926"`
-	Links    []Link   `json:"links,omitempty"`
+// RoleUser is a row of F95921, the role relationships: the user TOROLE is assigned the role FRROLE between
+// EffectiveFrom and EffectiveThru. A zero EffectiveThru means the assignment doesn't lapse.
+// Roles included in *ALL are active when the user signs in with the *ALL role, in the order of their Sequence;
+// the others are only active when the user signs in with that role.
+type RoleUser struct {
+	Role          string    `jde:"FRROLE"`
+	User          string    `jde:"TOROLE"`
+	EffectiveFrom time.Time `jde:"EFFFROM"`
+	EffectiveThru time.Time `jde:"EFFTHRU"`
+	IncludeInAll  bool      `jde:"FUSE"`
+	Sequence      int64     `jde:"SEQN"`
 }
 
-type RoleUsersResponse struct {
-	Resource Resource `json:"fs_DATABROWSE_F95921"`
-	Links    []Link   `json:"links,omitempty"`
+// Effective reports whether the assignment is in effect on the day of t. Both effective dates are included.
+func (r RoleUser) Effective(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if !r.EffectiveFrom.IsZero() && day.Before(r.EffectiveFrom) {
+		return false
+	}
+	return r.EffectiveThru.IsZero() || !day.After(r.EffectiveThru)
 }
 
 type ValidateTokenResponse struct {
@@ -68,12 +145,14 @@ type ValidateTokenResponse struct {
 }
 
 type ConfigResponse struct {
-	JasHost                   string           `json:"jasHost,omitempty"`
-	JasPort                   string           `json:"jasPort,omitempty"`
-	JasProtocol               string           `json:"jasProtocol,omitempty"`
-	DefaultEnvironment        string           `json:"defaultEnvironment,omitempty"`
-	DefaultRole               string           `json:"defaultRole,omitempty"`
-	AisVersion                string           `json:"aisVersion,omitempty"`
+	JasHost            string `json:"jasHost,omitempty"`
+	JasPort            string `json:"jasPort,omitempty"`
+	JasProtocol        string `json:"jasProtocol,omitempty"`
+	DefaultEnvironment string `json:"defaultEnvironment,omitempty"`
+	DefaultRole        string `json:"defaultRole,omitempty"`
+	AisVersion         string `json:"aisVersion,omitempty"`
+	// MaxPageSize is only reported by AIS servers whose administrator capped the rows returned at once.
+	MaxPageSize               int              `json:"maxPageSize,omitempty"`
 	CapabilityList            []CapabilityList `json:"capabilityList,omitempty"`
 	RequiredCapabilityMissing bool             `json:"requiredCapabilityMissing,omitempty"`
 }
@@ -0,0 +1,56 @@
+package jde
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRoleUserEffective(t *testing.T) {
+	noon := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	// late on March 1st in New York, already March 2nd in UTC.
+	evening := time.Date(2024, 3, 1, 22, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	tests := []struct {
+		name     string
+		from     string
+		thru     string
+		at       time.Time
+		wantFrom time.Time
+		want     bool
+	}{
+		{"blank dates", `""`, `"   "`, noon, time.Time{}, true},
+		{"zero dates", `0`, `"0"`, noon, time.Time{}, true},
+		{"julian from in the past", `"124046"`, `""`, noon, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), true},
+		{"julian from in the future", `124100`, `0`, noon, time.Date(2024, 4, 9, 0, 0, 0, 0, time.UTC), false},
+		{"julian thru in the past", `0`, `"124046"`, noon, time.Time{}, false},
+		{"from today", `"20240301"`, `""`, noon, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"from tomorrow", `"20240302"`, `""`, noon, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), false},
+		{"thru today", `""`, `"2024-03-01"`, noon, time.Time{}, true},
+		{"thru yesterday", `""`, `"02/29/2024"`, noon, time.Time{}, false},
+		{"thru today in the local day", `""`, `"20240301"`, evening, time.Time{}, true},
+		{"from tomorrow in the local day", `"20240302"`, `""`, evening, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := Row{
+				"F95921_FRROLE":  json.RawMessage(`"ADMIN"`),
+				"F95921_TOROLE":  json.RawMessage(`"JDOE"`),
+				"F95921_EFFFROM": json.RawMessage(tt.from),
+				"F95921_EFFTHRU": json.RawMessage(tt.thru),
+			}
+
+			var user RoleUser
+			if err := DecodeRow("F95921", row, &user); err != nil {
+				t.Fatal(err)
+			}
+			if !user.EffectiveFrom.Equal(tt.wantFrom) {
+				t.Errorf("got effective from %s, want %s", user.EffectiveFrom, tt.wantFrom)
+			}
+			if got := user.Effective(tt.at); got != tt.want {
+				t.Errorf("Effective(%s) = %t, want %t", tt.at, got, tt.want)
+			}
+		})
+	}
+}
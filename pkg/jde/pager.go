@@ -0,0 +1,398 @@
+package jde
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// PaginationMode selects how a Pager fetches the pages of a query.
+type PaginationMode string
+
+const (
+	// PaginationAuto pages with keys on AIS v1, which has no next links, and with next links on v2.
+	PaginationAuto PaginationMode = "auto"
+	// PaginationLinks follows the next links AIS hands out with every page but the last.
+	// AIS v1 has no next links, so there the whole result is fetched at once.
+	PaginationLinks PaginationMode = "links"
+	// PaginationKeyset sorts queries on their key and fetches every page with a condition on the key being greater
+	// than the last key of the previous page. Pages don't depend on cursors kept by the AIS server, so memory stays
+	// bounded on v1 and paging survives unreliable next links on v2.
+	PaginationKeyset PaginationMode = "keyset"
+)
+
+// ParsePaginationMode returns the PaginationMode named by mode, defaulting to PaginationAuto.
+func ParsePaginationMode(mode string) (PaginationMode, error) {
+	switch PaginationMode(mode) {
+	case "", PaginationAuto:
+		return PaginationAuto, nil
+	case PaginationLinks:
+		return PaginationLinks, nil
+	case PaginationKeyset:
+		return PaginationKeyset, nil
+	default:
+		return "", fmt.Errorf("unsupported pagination mode %q, expected one of %q, %q or %q", mode, PaginationAuto, PaginationLinks, PaginationKeyset)
+	}
+}
+
+// paginationMode returns the mode queries of the client are paged with.
+func (c *Client) paginationMode() PaginationMode {
+	if c.pagination != PaginationAuto && c.pagination != "" {
+		return c.pagination
+	}
+	if !c.features.NextPage {
+		return PaginationKeyset
+	}
+	return PaginationLinks
+}
+
+// Pager iterates over the rows of a dataservice query, decoding them into T, see DecodeRows.
+// A Pager is not safe for concurrent use.
+type Pager[T any] struct {
+	c       *Client
+	request DataRequestBody
+	// key is the qualified key column of the query. Without a key, pages can only be reached through next links.
+	key string
+	// ties tells whether rows may share a key, see QueryBuilder.Key.
+	ties        bool
+	keyset      bool
+	pageSize    int
+	fingerprint string
+	// after is the key of the last row returned.
+	after string
+	// limit caps the rows returned, delivered counts them.
+	limit     int
+	delivered int
+	token     string
+	started   bool
+	done      bool
+	err       error
+}
+
+// NewPager returns a pager that follows the next links of the data request. Nothing is sent before the first call
+// to Next. Its tokens are next links, which only live as long as the AIS session; see Paginate for tokens that
+// outlive it.
+func NewPager[T any](c *Client, dataRequest DataRequestBody) *Pager[T] {
+	return &Pager[T]{c: c, request: dataRequest, limit: c.limits.RecordLimit}
+}
+
+// Paginate returns a pager over the rows of the query, paged as configured with ClientOptions.Pagination.
+// Queries without a Key, or without next pages, are always paged with next links. AIS v1 returns every row of those
+// at once.
+//
+// Tokens of queries with a Key hold the last key returned, so they can be resumed in another AIS session or after
+// a restart. Next links are still followed while the session that handed them out is alive.
+func Paginate[T any](c *Client, q *QueryBuilder) *Pager[T] {
+	p := &Pager[T]{c: c, key: q.key, ties: q.ties, limit: c.limits.RecordLimit}
+	if q.pageSize == "" {
+		q.PageSize(c.limits.pageSize())
+	}
+
+	switch {
+	case c.paginationMode() == PaginationKeyset && q.key != "" && q.nextPage:
+		pageSize, err := strconv.Atoi(q.pageSize)
+		if err != nil || pageSize <= 0 {
+			p.err = fmt.Errorf("paging with keys needs a numeric page size, got %q", q.pageSize)
+			return p
+		}
+		if q.matchType == MatchAny {
+			p.err = fmt.Errorf("paging with keys needs queries that match all conditions")
+			return p
+		}
+
+		// AIS must not hand out next links, the next page is requested by key.
+		q.NextPage(false)
+		p.keyset = true
+		p.pageSize = pageSize
+	case !c.features.NextPage && q.nextPage:
+		q.PageSize(noMax)
+	}
+
+	p.request, p.err = q.Build()
+	p.fingerprint = fingerprint(p.request)
+	return p
+}
+
+// Resume continues paging from a token returned by Token. An empty token starts from the first page.
+func (p *Pager[T]) Resume(token string) *Pager[T] {
+	if token == "" || p.err != nil {
+		return p
+	}
+	p.token = token
+	p.started = true
+
+	if p.key == "" {
+		return p
+	}
+
+	t, err := decodePageToken(token)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	if t.Table != p.request.TargetName || t.Key != p.key || t.Fingerprint != p.fingerprint {
+		p.err = fmt.Errorf("page token was handed out for another query on %s", t.Table)
+		return p
+	}
+	p.after = t.After
+	p.delivered = t.Delivered
+
+	return p
+}
+
+// Next returns the next page of rows. It returns no rows once Done reports true.
+func (p *Pager[T]) Next(ctx context.Context) ([]T, error) {
+	var rows []T
+	err := p.NextFunc(ctx, func(row T) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// NextFunc hands the rows of the next page to emit as they are read from the response, so that only what emit keeps
+// is held in memory. Like Next, it does nothing once Done reports true.
+func (p *Pager[T]) NextFunc(ctx context.Context, emit func(T) error) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.Done() {
+		return nil
+	}
+
+	r := &pageReader[T]{p: p, emit: emit}
+	page, err := p.fetch(ctx, r.read)
+	if err != nil {
+		return err
+	}
+
+	more := page.nextUrl != ""
+	if p.keyset {
+		more = page.rows >= p.pageSize || page.moreRecords
+	}
+
+	return p.advance(ctx, page, r, more)
+}
+
+// pageReader decodes the rows of a page for a pager and counts them, stopping at the record limit.
+type pageReader[T any] struct {
+	p       *Pager[T]
+	emit    func(T) error
+	count   int
+	limited bool
+	last    Row
+	// held keeps the rows of the last key read when rows may share keys, until a row of another key shows they are
+	// complete. lastKey is the key of the last row handed to emit.
+	held    []T
+	heldKey string
+	lastKey string
+}
+
+func (r *pageReader[T]) read(table string, row Row) error {
+	if r.p.limit > 0 && r.p.delivered+r.count >= r.p.limit {
+		r.limited = true
+		return errStopRows
+	}
+
+	var v T
+	if err := DecodeRow(table, row, &v); err != nil {
+		return fmt.Errorf("error decoding row of %s: %w", table, err)
+	}
+	r.count++
+	r.last = row
+	if !r.p.ties {
+		return r.emit(v)
+	}
+
+	key, err := rawText(row[columnKey(table, r.p.key)])
+	if err != nil {
+		return fmt.Errorf("error reading key %s: %w", r.p.key, err)
+	}
+	if len(r.held) > 0 && key != r.heldKey {
+		if err := r.flush(); err != nil {
+			return err
+		}
+	}
+	r.held = append(r.held, v)
+	r.heldKey = key
+	return nil
+}
+
+// flush hands the rows held to emit.
+func (r *pageReader[T]) flush() error {
+	held := r.held
+	r.held = nil
+	r.lastKey = r.heldKey
+	for _, v := range held {
+		if err := r.emit(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release ends a page of a query whose rows may share keys. The rows of the last key are handed to emit after the
+// last page, otherwise they are left out, as they may continue on the next page, which starts with them.
+func (r *pageReader[T]) release(table string, more bool) error {
+	if !more {
+		return r.flush()
+	}
+	if r.count > 0 && len(r.held) == r.count {
+		return fmt.Errorf("the %d rows of a page of %s share the key %s %q, raise the page size", r.count, table, r.p.key, r.heldKey)
+	}
+	r.count -= len(r.held)
+	r.held = nil
+	return nil
+}
+
+// advance moves the pager past the page r read. more tells whether rows are left after it.
+func (p *Pager[T]) advance(ctx context.Context, page rowsPage, r *pageReader[T], more bool) error {
+	p.started = true
+
+	if requested, err := strconv.Atoi(p.request.MaxPageSize); err == nil && page.rows < requested && page.moreRecords {
+		p.c.warnShortPage(ctx, page.table, requested, page.rows)
+	}
+
+	if p.limit > 0 && p.delivered+r.count >= p.limit && (more || r.limited) {
+		ctxzap.Extract(ctx).Info("baton-jd-edwards: record limit reached, skipping the remaining rows",
+			zap.String("table", page.table),
+			zap.Int("record_limit", p.limit),
+		)
+		more = false
+	}
+
+	if p.ties {
+		if err := r.release(page.table, more); err != nil {
+			return err
+		}
+	}
+	p.delivered += r.count
+
+	if p.key == "" || !more {
+		p.token = ""
+		if more {
+			p.token = page.nextUrl
+		}
+		p.done = !more
+		return nil
+	}
+
+	if r.count > 0 {
+		after := r.lastKey
+		if !p.ties {
+			var err error
+			after, err = rawText(r.last[columnKey(page.table, p.key)])
+			if err != nil {
+				return fmt.Errorf("error reading key %s of the last row: %w", p.key, err)
+			}
+		}
+		if after == "" {
+			return fmt.Errorf("key %s is missing from the rows of %s", p.key, page.table)
+		}
+		p.after = after
+	}
+
+	var err error
+	p.token, err = pageToken{
+		Table:       p.request.TargetName,
+		Key:         p.key,
+		After:       p.after,
+		Delivered:   p.delivered,
+		Fingerprint: p.fingerprint,
+	}.encode()
+	if err != nil {
+		return err
+	}
+	// the next link would skip the rows left out of the page.
+	if page.nextUrl != "" && !p.ties {
+		p.c.links.Store(p.token, page.nextUrl)
+	}
+
+	return nil
+}
+
+// fetch requests the page following the token. The next link handed out with the previous page is used while this
+// client still knows it, otherwise the page is requested by key.
+func (p *Pager[T]) fetch(ctx context.Context, fn rowFunc) (rowsPage, error) {
+	if !p.started {
+		return p.c.listRows(ctx, p.request, fn)
+	}
+
+	if p.key == "" {
+		return p.c.fetchMoreRows(ctx, p.token, fn)
+	}
+
+	if v, ok := p.c.links.LoadAndDelete(p.token); ok {
+		if nextUrl, _ := v.(string); nextUrl != "" {
+			return p.c.fetchMoreRows(ctx, nextUrl, fn)
+		}
+	}
+
+	request := p.request
+	query := Query{AutoFind: true}
+	if request.Query != nil {
+		query = *request.Query
+	}
+	query.Condition = append(append([]Condition{}, query.Condition...), Condition{
+		ControlId: p.key,
+		Operator:  string(OpGreater),
+		Value:     []Value{{Content: p.after, SpecialValueID: literal}},
+	})
+	request.Query = &query
+
+	return p.c.listRows(ctx, request, fn)
+}
+
+// Done reports whether the last page was returned.
+func (p *Pager[T]) Done() bool {
+	return p.done
+}
+
+// Token returns where the next page starts, to continue paging later with Resume. It is empty after the last page.
+func (p *Pager[T]) Token() string {
+	return p.token
+}
+
+// ForEach calls fn for every remaining row as it is read, fetching pages as needed. Rows aren't collected, so
+// memory stays bounded by a single row whatever the page size. It stops at the first error fn returns.
+func (p *Pager[T]) ForEach(ctx context.Context, fn func(T) error) error {
+	for !p.Done() {
+		if err := p.NextFunc(ctx, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stream sends every remaining row on the returned channel, which is closed after the last row.
+// The error channel receives the error paging stopped on, if any, and is closed with the rows channel.
+// Cancelling ctx stops paging.
+func (p *Pager[T]) Stream(ctx context.Context) (<-chan T, <-chan error) {
+	rows := make(chan T)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(rows)
+
+		err := p.ForEach(ctx, func(row T) error {
+			select {
+			case rows <- row:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return rows, errs
+}
@@ -0,0 +1,157 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newBrowseServer serves F0092 rows in pages of two, honouring a GREATER condition on USER.
+func newBrowseServer(t *testing.T, users []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req DataRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("error decoding request: %v", err)
+		}
+
+		after := ""
+		if req.Query != nil {
+			for _, c := range req.Query.Condition {
+				if c.Operator == string(OpGreater) {
+					after = c.Value[0].Content
+				}
+			}
+		}
+
+		var rows []string
+		for _, u := range users {
+			if u > after && len(rows) < 2 {
+				rows = append(rows, fmt.Sprintf(`{"F0092_USER": %q}`, u+"   "))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"fs_DATABROWSE_F0092": {"data": {"gridData": {"rowset": [%s]}}}}`, strings.Join(rows, ","))
+	}))
+}
+
+func TestPagerResumesInNewClient(t *testing.T) {
+	srv := newBrowseServer(t, []string{"A", "B", "C", "D", "E"})
+	defer srv.Close()
+
+	newClient := func() *Client {
+		c, err := NewClient(srv.Client(), srv.URL, Credentials{AuthMode: AuthModeBasic, Username: "u", Password: "p"},
+			ClientOptions{Pagination: PaginationKeyset, Limits: QueryLimits{PageSize: 2, RecordLimit: 4}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	ctx := context.Background()
+	var got []string
+	token := ""
+	for {
+		// every page is fetched by a new client, as after a restart of the connector.
+		p := newClient().Users().Resume(token)
+		users, err := p.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range users {
+			got = append(got, u.ID)
+		}
+		token = p.Token()
+		if token == "" {
+			break
+		}
+	}
+
+	// the record limit stops paging after the fourth row.
+	if strings.Join(got, ",") != "A,B,C,D" {
+		t.Errorf("got users %v", got)
+	}
+
+	tok, err := pageToken{Table: "F0092", Key: "F0092.USER", After: "B", Fingerprint: "other"}.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newClient().Users().Resume(tok).Next(ctx); err == nil {
+		t.Error("expected a token of another query to be refused")
+	}
+}
+
+func TestPagerKeyTies(t *testing.T) {
+	// roles are assigned to users once per effective date, the rows are sorted on TOROLE then EFFFROM.
+	rows := [][2]string{{"A", "20240101"}, {"B", "20240101"}, {"B", "20240102"}, {"B", "20240103"}, {"C", "20240101"}, {"C", "20240102"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req DataRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("error decoding request: %v", err)
+		}
+		pageSize, _ := strconv.Atoi(req.MaxPageSize)
+
+		after := ""
+		for _, c := range req.Query.Condition {
+			if c.Operator == string(OpGreater) {
+				after = c.Value[0].Content
+			}
+		}
+
+		var page []string
+		for _, row := range rows {
+			if row[0] > after && len(page) < pageSize {
+				page = append(page, fmt.Sprintf(`{"F95921_TOROLE": %q, "F95921_EFFFROM": %q}`, row[0], row[1]))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"fs_DATABROWSE_F95921": {"data": {"gridData": {"rowset": [%s]}}}}`, strings.Join(page, ","))
+	}))
+	defer srv.Close()
+
+	pages := func(pageSize int) ([]string, error) {
+		c, err := NewClient(srv.Client(), srv.URL, Credentials{AuthMode: AuthModeBasic, Username: "u", Password: "p"},
+			ClientOptions{Pagination: PaginationKeyset, Limits: QueryLimits{PageSize: pageSize}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		token := ""
+		for {
+			p := c.RoleUsers("R").Resume(token)
+			users, err := p.Next(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			var page []string
+			for _, u := range users {
+				page = append(page, u.User+u.EffectiveFrom.Format("2"))
+			}
+			got = append(got, strings.Join(page, " "))
+			token = p.Token()
+			if token == "" {
+				return got, nil
+			}
+		}
+	}
+
+	// the rows of B and C don't fit in the first and second pages, they are returned with the next ones.
+	got, err := pages(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "A1,B1 B2 B3,C1 C2" {
+		t.Errorf("got pages %v", got)
+	}
+
+	if _, err := pages(3); err == nil {
+		t.Error("expected an error when a key has as many rows as a page holds")
+	}
+}
@@ -0,0 +1,60 @@
+package jde
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// pageToken locates the next page of a keyed query without relying on the AIS session or its cursors:
+// the next page holds the rows whose key is greater than After.
+type pageToken struct {
+	Table string `json:"t"`
+	Key   string `json:"k"`
+	After string `json:"a"`
+	// Delivered counts the rows returned so far, for the record limit.
+	Delivered int `json:"n,omitempty"`
+	// Fingerprint identifies the query, so a token isn't resumed with a query it wasn't handed out for.
+	Fingerprint string `json:"f"`
+}
+
+func (t pageToken) encode() (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodePageToken(token string) (pageToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageToken{}, fmt.Errorf("invalid page token: %w", err)
+	}
+
+	var t pageToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return pageToken{}, fmt.Errorf("invalid page token: %w", err)
+	}
+	return t, nil
+}
+
+// fingerprint hashes what selects and orders the rows of a data request. The page size and the session fields
+// don't change which row follows another, so they are left out.
+func fingerprint(dataRequest DataRequestBody) string {
+	b, _ := json.Marshal(struct {
+		TargetName       string
+		ReturnControlIDs string
+		Query            *Query
+		Aggregation      *Aggregation
+	}{
+		dataRequest.TargetName,
+		dataRequest.ReturnControlIDs,
+		dataRequest.Query,
+		dataRequest.Aggregation,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,226 @@
+package jde
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operator is the comparison of a dataservice query condition.
+type Operator string
+
+const (
+	OpEqual        Operator = "EQUAL"
+	OpNotEqual     Operator = "NOT_EQUAL"
+	OpLess         Operator = "LESS"
+	OpLessEqual    Operator = "LESS_EQUAL"
+	OpGreater      Operator = "GREATER"
+	OpGreaterEqual Operator = "GREATER_EQUAL"
+	// OpBetween matches values between two bounds, both included.
+	OpBetween Operator = "BETWEEN"
+	// OpList matches any of the given values.
+	OpList       Operator = "LIST"
+	OpStartsWith Operator = "STR_START_WITH"
+	OpEndsWith   Operator = "STR_END_WITH"
+	OpContains   Operator = "STR_CONTAIN"
+	OpBlank      Operator = "STR_BLANK"
+	OpNotBlank   Operator = "STR_NOT_BLANK"
+)
+
+// MatchType tells whether a row must match all the conditions of a query, or any of them.
+type MatchType string
+
+const (
+	MatchAll MatchType = "MATCH_ALL"
+	MatchAny MatchType = "MATCH_ANY"
+)
+
+// SortDirection orders the rows of a query.
+type SortDirection string
+
+const (
+	Ascending  SortDirection = "ASC"
+	Descending SortDirection = "DESC"
+)
+
+const (
+	// literal is the special value id of values that are compared as they are.
+	literal = "LITERAL"
+	// SpecialToday compares against the current date of the AIS server.
+	SpecialToday = "TODAY"
+)
+
+// Aggregation holds the ordering of a dataservice request.
+type Aggregation struct {
+	OrderBy []OrderBy `json:"orderBy,omitempty"`
+}
+
+type OrderBy struct {
+	Column    string        `json:"column"`
+	Direction SortDirection `json:"direction"`
+}
+
+// QueryBuilder builds a dataservice BROWSE request on a single table.
+// Columns may be given as "USER" or "F0092.USER", the table is prepended when it is missing.
+//
+//	jde.Browse("F0092").
+//		Columns("USER", "UGRP").
+//		Where("UGRP", jde.OpEqual, "").
+//		OrderBy("USER", jde.Ascending).
+//		Build()
+type QueryBuilder struct {
+	table      string
+	columns    []string
+	conditions []Condition
+	matchType  MatchType
+	orderBy    []OrderBy
+	pageSize   string
+	nextPage   bool
+	key        string
+	// ties tells whether rows may share a key, see Key.
+	ties bool
+	err  error
+}
+
+// Browse starts a query on table. Without PageSize, Paginate uses the page size of the client.
+func Browse(table string) *QueryBuilder {
+	return &QueryBuilder{
+		table:    table,
+		nextPage: true,
+	}
+}
+
+// Columns sets the columns returned for every row.
+func (q *QueryBuilder) Columns(columns ...string) *QueryBuilder {
+	for _, column := range columns {
+		q.columns = append(q.columns, q.qualify(column))
+	}
+	return q
+}
+
+// Where adds a condition comparing the column with literal values.
+func (q *QueryBuilder) Where(column string, op Operator, values ...string) *QueryBuilder {
+	vals := make([]Value, 0, len(values))
+	for _, v := range values {
+		vals = append(vals, Value{Content: v, SpecialValueID: literal})
+	}
+	return q.WhereValues(column, op, vals...)
+}
+
+// WhereValues adds a condition comparing the column with values that may be special values, like SpecialToday.
+func (q *QueryBuilder) WhereValues(column string, op Operator, values ...Value) *QueryBuilder {
+	if err := checkArity(op, len(values)); err != nil && q.err == nil {
+		q.err = fmt.Errorf("condition on %s: %w", column, err)
+	}
+
+	q.conditions = append(q.conditions, Condition{
+		ControlId: q.qualify(column),
+		Operator:  string(op),
+		Value:     values,
+	})
+	return q
+}
+
+// Match sets whether rows must match all the conditions, the AIS default, or any of them.
+func (q *QueryBuilder) Match(matchType MatchType) *QueryBuilder {
+	q.matchType = matchType
+	return q
+}
+
+// OrderBy sorts the rows on column. Calling it again adds a column to sort on.
+func (q *QueryBuilder) OrderBy(column string, direction SortDirection) *QueryBuilder {
+	q.orderBy = append(q.orderBy, OrderBy{Column: q.qualify(column), Direction: direction})
+	return q
+}
+
+// Key sets the column that identifies rows of the query, and sorts on it, so that pages can be fetched by key
+// ranges, see PaginationKeyset. The values of the column must be unique among the rows of the query, unless
+// tieBreakers are given: rows sharing a key are then sorted on those columns too, and pages end with the last key
+// whose rows they hold completely, so the rows of a key are always returned together. No key may be shared by as
+// many rows as a page holds.
+func (q *QueryBuilder) Key(column string, tieBreakers ...string) *QueryBuilder {
+	q.key = q.qualify(column)
+	q.ties = len(tieBreakers) > 0
+	q.OrderBy(column, Ascending)
+	for _, c := range tieBreakers {
+		q.OrderBy(c, Ascending)
+	}
+	return q
+}
+
+// PageSize sets how many rows AIS returns at once, "No max" returns every row in one page.
+func (q *QueryBuilder) PageSize(size string) *QueryBuilder {
+	q.pageSize = size
+	return q
+}
+
+// NextPage sets whether AIS hands out a link to the next page, instead of dropping the remaining rows.
+func (q *QueryBuilder) NextPage(enabled bool) *QueryBuilder {
+	q.nextPage = enabled
+	return q
+}
+
+// Build returns the data request, or the first invalid condition that was added.
+func (q *QueryBuilder) Build() (DataRequestBody, error) {
+	if q.err != nil {
+		return DataRequestBody{}, q.err
+	}
+	if q.table == "" {
+		return DataRequestBody{}, fmt.Errorf("query has no table")
+	}
+
+	dataRequest := DataRequestBody{
+		TargetName:               q.table,
+		TargetType:               "table",
+		DataServiceType:          "BROWSE",
+		FindOnEntry:              "true",
+		ReturnControlIDs:         strings.Join(q.columns, "|"),
+		MaxPageSize:              q.pageSize,
+		EnableNextPageProcessing: fmt.Sprint(q.nextPage),
+		OutputType:               outputType,
+	}
+
+	if len(q.conditions) > 0 {
+		dataRequest.Query = &Query{
+			AutoFind:  true,
+			MatchType: q.matchType,
+			Condition: q.conditions,
+		}
+	}
+
+	if len(q.orderBy) > 0 {
+		dataRequest.Aggregation = &Aggregation{OrderBy: q.orderBy}
+	}
+
+	return dataRequest, nil
+}
+
+func (q *QueryBuilder) qualify(column string) string {
+	if strings.Contains(column, ".") {
+		return column
+	}
+	return q.table + "." + column
+}
+
+func checkArity(op Operator, n int) error {
+	switch op {
+	case OpBlank, OpNotBlank:
+		if n != 0 {
+			return fmt.Errorf("%s takes no value, got %d", op, n)
+		}
+	case OpBetween:
+		if n != 2 {
+			return fmt.Errorf("%s takes 2 values, got %d", op, n)
+		}
+	case OpList:
+		if n == 0 {
+			return fmt.Errorf("%s takes at least one value", op)
+		}
+	case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual, OpStartsWith, OpEndsWith, OpContains:
+		if n != 1 {
+			return fmt.Errorf("%s takes 1 value, got %d", op, n)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", op)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package jde
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckArity(t *testing.T) {
+	tests := []struct {
+		op      Operator
+		n       int
+		wantErr bool
+	}{
+		{OpEqual, 1, false},
+		{OpEqual, 0, true},
+		{OpGreater, 2, true},
+		{OpStartsWith, 1, false},
+		{OpBetween, 2, false},
+		{OpBetween, 1, true},
+		{OpBetween, 3, true},
+		{OpList, 1, false},
+		{OpList, 5, false},
+		{OpList, 0, true},
+		{OpBlank, 0, false},
+		{OpNotBlank, 1, true},
+		{Operator("LIKE"), 1, true},
+	}
+
+	for _, tt := range tests {
+		err := checkArity(tt.op, tt.n)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkArity(%s, %d) = %v, want error %t", tt.op, tt.n, err, tt.wantErr)
+		}
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	dataRequest, err := Browse("F0092").
+		Columns("USER", "F0101.ALPH").
+		Where("UGRP", OpNotEqual, GroupProfile).
+		WhereValues("UPMJ", OpLessEqual, Value{SpecialValueID: SpecialToday}).
+		Match(MatchAny).
+		Key("USER").
+		PageSize("50").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dataRequest.TargetName != "F0092" || dataRequest.ReturnControlIDs != "F0092.USER|F0101.ALPH" ||
+		dataRequest.MaxPageSize != "50" || dataRequest.EnableNextPageProcessing != "true" {
+		t.Errorf("unexpected data request %+v", dataRequest)
+	}
+	if q := dataRequest.Query; q == nil || q.MatchType != MatchAny || len(q.Condition) != 2 ||
+		q.Condition[0].ControlId != "F0092.UGRP" || q.Condition[0].Value[0].SpecialValueID != literal ||
+		q.Condition[1].Value[0].SpecialValueID != SpecialToday {
+		t.Errorf("unexpected query %+v", dataRequest.Query)
+	}
+	if a := dataRequest.Aggregation; a == nil || len(a.OrderBy) != 1 || a.OrderBy[0] != (OrderBy{Column: "F0092.USER", Direction: Ascending}) {
+		t.Errorf("unexpected aggregation %+v", dataRequest.Aggregation)
+	}
+
+	tests := []struct {
+		name    string
+		q       *QueryBuilder
+		wantErr string
+	}{
+		{"no table", Browse("").Columns("USER"), "query has no table"},
+		{"missing value", Browse("F0092").Where("USER", OpEqual), "condition on USER: EQUAL takes 1 value, got 0"},
+		{"first invalid condition", Browse("F0092").Where("USER", OpBetween, "A").Where("UGRP", OpList), "condition on USER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.q.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaginateKeysetValidation(t *testing.T) {
+	c, err := NewClient(nil, "https://ais", Credentials{AuthMode: AuthModeBasic, Username: "u", Password: "p"}, ClientOptions{Pagination: PaginationKeyset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		q       *QueryBuilder
+		wantErr string
+	}{
+		{"page size", Browse("F0092").Key("USER").PageSize(noMax), "numeric page size"},
+		{"match any", Browse("F0092").Key("USER").PageSize("10").Match(MatchAny), "match all conditions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Paginate[User](c, tt.q)
+			if p.err == nil || !strings.Contains(p.err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", p.err, tt.wantErr)
+			}
+		})
+	}
+
+	p := Paginate[User](c, Browse("F0092").Key("USER").PageSize("10"))
+	if p.err != nil || !p.keyset || p.pageSize != 10 || p.request.EnableNextPageProcessing != "false" {
+		t.Errorf("unexpected keyset pager %+v", p)
+	}
+}
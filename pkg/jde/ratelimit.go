@@ -0,0 +1,118 @@
+package jde
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RateLimitOptions throttles the requests sent to the AIS server. Zero values mean no limit.
+type RateLimitOptions struct {
+	// RequestsPerSecond spaces requests evenly, so that no more than this many start every second.
+	RequestsPerSecond int
+	// MaxInFlight caps how many requests are waiting for an answer of the AIS server at the same time.
+	MaxInFlight int
+}
+
+// limiter enforces RateLimitOptions for every request, retries included.
+type limiter struct {
+	opts     RateLimitOptions
+	interval time.Duration
+	inFlight chan struct{}
+
+	mtx  sync.Mutex
+	next time.Time
+}
+
+func newLimiter(opts RateLimitOptions) *limiter {
+	l := &limiter{opts: opts}
+	if opts.RequestsPerSecond > 0 {
+		l.interval = time.Second / time.Duration(opts.RequestsPerSecond)
+	}
+	if opts.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, opts.MaxInFlight)
+	}
+	return l
+}
+
+// wait blocks until the request may be sent. The returned func must be called once the response has been read.
+func (l *limiter) wait(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-l.inFlight }
+	}
+
+	if l.interval == 0 {
+		return release, nil
+	}
+
+	l.mtx.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	start := l.next
+	l.next = l.next.Add(l.interval)
+	l.mtx.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return release, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// description reports how much of the current second's budget is left, or nil when requests aren't rate limited.
+func (l *limiter) description() *v2.RateLimitDescription {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mtx.Lock()
+	next := l.next
+	l.mtx.Unlock()
+
+	now := time.Now()
+	limit := int64(l.opts.RequestsPerSecond)
+	remaining := limit
+	resetAt := now
+	if next.After(now) {
+		queued := int64(next.Sub(now) / l.interval)
+		remaining = max(limit-queued, 0)
+		resetAt = next
+	}
+
+	status := v2.RateLimitDescription_STATUS_OK
+	if remaining == 0 {
+		status = v2.RateLimitDescription_STATUS_OVERLIMIT
+	}
+
+	return &v2.RateLimitDescription{
+		Status:    status,
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   timestamppb.New(resetAt),
+	}
+}
+
+// RateLimit describes the client side rate limit, so baton can pace its calls. It is nil without a rate limit.
+func (c *Client) RateLimit() *v2.RateLimitDescription {
+	return c.limiter.description()
+}
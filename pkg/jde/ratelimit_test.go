@@ -0,0 +1,84 @@
+package jde
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+func TestLimiterRate(t *testing.T) {
+	l := newLimiter(RateLimitOptions{RequestsPerSecond: 50})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		release, err := l.wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	// the first request starts at once, the five others 20ms apart.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("6 requests at 50 per second took %s", elapsed)
+	}
+	if d := l.description(); d == nil || d.Limit != 50 || d.Status != v2.RateLimitDescription_STATUS_OK {
+		t.Errorf("unexpected rate limit description %v", d)
+	}
+
+	// more than a second's worth of requests is queued.
+	l.next = time.Now().Add(2 * time.Second)
+	if d := l.description(); d.Remaining != 0 || d.Status != v2.RateLimitDescription_STATUS_OVERLIMIT {
+		t.Errorf("unexpected rate limit description %v", d)
+	}
+	if newLimiter(RateLimitOptions{}).description() != nil {
+		t.Error("expected no rate limit description without a rate limit")
+	}
+}
+
+func TestLimiterInFlight(t *testing.T) {
+	l := newLimiter(RateLimitOptions{MaxInFlight: 2})
+
+	first, err := l.wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a third request to wait for a slot, got %v", err)
+	}
+
+	first()
+	if _, err := l.wait(context.Background()); err != nil {
+		t.Errorf("expected a released slot to be reused, got %v", err)
+	}
+}
+
+func TestLimiterCancelReleasesSlot(t *testing.T) {
+	l := newLimiter(RateLimitOptions{RequestsPerSecond: 1, MaxInFlight: 1})
+
+	release, err := l.wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	// the slot is free, but the next request has to wait for the rate limit and is cancelled meanwhile.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := l.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the request to be cancelled, got %v", err)
+	}
+
+	if len(l.inFlight) != 0 {
+		t.Errorf("expected the cancelled request to release its slot, %d in flight", len(l.inFlight))
+	}
+}
@@ -0,0 +1,195 @@
+package jde
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+
+	// breakerThreshold is the number of consecutive server errors after which requests fail fast.
+	breakerThreshold = 5
+	// breakerCooldown is how long requests fail fast before the AIS server is tried again.
+	breakerCooldown = time.Minute
+)
+
+// RetryOptions configures how idempotent requests are retried when the AIS server fails.
+type RetryOptions struct {
+	// MaxRetries is the number of times a failed request is retried. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, it doubles with every attempt. Defaults to one second.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts. Defaults to 30 seconds.
+	MaxDelay time.Duration
+}
+
+// backoff returns the jittered delay before the given retry, counting from zero.
+func (o RetryOptions) backoff(attempt int) time.Duration {
+	base := o.BaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	maxDelay := o.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	delay := maxDelay
+	if attempt < 30 && base<<attempt < maxDelay {
+		delay = base << attempt
+	}
+
+	// equal jitter: wait at least half the delay, so retries of concurrent requests spread out.
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)) //nolint:gosec // jitter doesn't need a secure source.
+}
+
+// idempotent reports whether sending the payload again can't change anything on the AIS server:
+// BROWSE data requests and batches of them, and the requests without a body that fetch the config or the next page.
+func idempotent(payload interface{}) bool {
+	switch p := payload.(type) {
+	case nil:
+		return true
+	case DataRequestBody:
+		return p.DataServiceType == "BROWSE"
+	case BatchRequestBody:
+		for _, dataRequest := range p.DataRequests {
+			if !idempotent(dataRequest) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable reports whether the request failed in a way another attempt may not.
+func retryable(err error) bool {
+	var open *circuitOpenError
+	if errors.As(err, &open) {
+		return false
+	}
+
+	var interrupted *interruptedError
+	if errors.As(err, &interrupted) {
+		return false
+	}
+
+	var aisErr *AISError
+	if errors.As(err, &aisErr) {
+		return aisErr.Kind == ErrorServer || aisErr.code() == codes.Unavailable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// withRetry calls send until it succeeds, fails with an error that isn't retryable, or runs out of retries.
+func (c *Client) withRetry(ctx context.Context, retry bool, send func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.breaker.allow(); err != nil {
+			return err
+		}
+
+		err := send()
+		c.breaker.record(err)
+		if err == nil || !retry || attempt >= c.retry.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		delay := c.retry.backoff(attempt)
+		ctxzap.Extract(ctx).Warn(
+			"baton-jd-edwards: AIS request failed, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// circuitBreaker makes requests fail fast while the AIS server keeps answering with server errors,
+// instead of piling more work onto a JAS server that is restarting or overloaded.
+type circuitBreaker struct {
+	mtx       sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+type circuitOpenError struct {
+	failures int
+	until    time.Time
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf(
+		"AIS server failed %d requests in a row, not sending requests until %s",
+		e.failures, e.until.Format(time.RFC3339),
+	)
+}
+
+func (e *circuitOpenError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+func (b *circuitBreaker) allow() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if time.Now().Before(b.openUntil) {
+		return &circuitOpenError{failures: b.failures, until: b.openUntil}
+	}
+	return nil
+}
+
+// record counts consecutive server errors. Once the breaker tripped, a single failure after the cooldown trips it again.
+func (b *circuitBreaker) record(err error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !serverFailure(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// serverFailure reports whether the error means the AIS server itself is unhealthy.
+func serverFailure(err error) bool {
+	var aisErr *AISError
+	if errors.As(err, &aisErr) {
+		return aisErr.Kind == ErrorServer
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package jde
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errServer  = &AISError{StatusCode: http.StatusServiceUnavailable, Kind: ErrorServer}
+	errRequest = &AISError{StatusCode: http.StatusBadRequest, Kind: ErrorUnknown}
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", errServer, true},
+		{"wrapped server error", fmt.Errorf("error fetching users: %w", errServer), true},
+		{"too many requests", &AISError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad request", errRequest, false},
+		{"session expired", &AISError{StatusCode: statusInvalidToken, Kind: ErrorSessionExpired}, false},
+		{"table security", &AISError{StatusCode: http.StatusForbidden, Kind: ErrorTableSecurity}, false},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, "timeout"), true},
+		{"rows already handed out", &interruptedError{err: errServer}, false},
+		{"circuit open", &circuitOpenError{failures: breakerThreshold, until: time.Now()}, false},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	browse := DataRequestBody{DataServiceType: "BROWSE"}
+	update := DataRequestBody{DataServiceType: "UPDATE"}
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    bool
+	}{
+		{"no body", nil, true},
+		{"browse", browse, true},
+		{"update", update, false},
+		{"batch of browses", BatchRequestBody{DataRequests: []DataRequestBody{browse, browse}}, true},
+		{"batch with an update", BatchRequestBody{DataRequests: []DataRequestBody{browse, update}}, false},
+		{"app stack", AppStackRequest{Action: appStackExecute}, false},
+		{"login", AuthRequestBody{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idempotent(tt.payload); got != tt.want {
+				t.Errorf("idempotent(%#v) = %t, want %t", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		idempotent bool
+		errs       []error
+		wantCalls  int
+		wantErr    error
+	}{
+		{"success", true, []error{nil}, 1, nil},
+		{"recovers", true, []error{errServer, errServer, nil}, 3, nil},
+		{"runs out of retries", true, []error{errServer, errServer, errServer, nil}, 3, errServer},
+		{"not retryable", true, []error{errRequest, nil}, 1, errRequest},
+		{"not idempotent", false, []error{errServer, nil}, 1, errServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{retry: RetryOptions{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+			calls := 0
+			err := c.withRetry(context.Background(), tt.idempotent, func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	c := &Client{}
+	fail := func() error { return errServer }
+
+	// a client error in between doesn't count as a server failure and starts over.
+	for i := 0; i < breakerThreshold-1; i++ {
+		_ = c.withRetry(context.Background(), false, fail)
+	}
+	_ = c.withRetry(context.Background(), false, func() error { return errRequest })
+	for i := 0; i < breakerThreshold-1; i++ {
+		if err := c.withRetry(context.Background(), false, fail); !errors.Is(err, errServer) {
+			t.Fatalf("failure %d: got error %v, want the server error", i+1, err)
+		}
+	}
+
+	// the failure that reaches the threshold trips the breaker.
+	_ = c.withRetry(context.Background(), false, fail)
+	sent := false
+	err := c.withRetry(context.Background(), true, func() error {
+		sent = true
+		return nil
+	})
+	var open *circuitOpenError
+	if !errors.As(err, &open) || sent {
+		t.Fatalf("expected the breaker to fail fast, got %v", err)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("got code %s, want %s", status.Code(err), codes.Unavailable)
+	}
+
+	// once the cooldown elapsed a request goes through, and a single failure trips the breaker again.
+	c.breaker.openUntil = time.Now().Add(-time.Millisecond)
+	_ = c.withRetry(context.Background(), false, fail)
+	if err := c.breaker.allow(); !errors.As(err, &open) {
+		t.Fatalf("expected the breaker to trip again, got %v", err)
+	}
+
+	// a success after the cooldown closes the breaker.
+	c.breaker.openUntil = time.Now().Add(-time.Millisecond)
+	if err := c.withRetry(context.Background(), false, func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.withRetry(context.Background(), false, fail)
+	if err := c.breaker.allow(); err != nil {
+		t.Errorf("expected a single failure after a success not to trip the breaker, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package jde
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dataBrowsePrefix prefixes the key holding the grid in the response of a BROWSE request, followed by the table.
+const dataBrowsePrefix = "fs_DATABROWSE_"
+
+// relNext is the relation of the link to the next page of a rowset.
+const relNext = "next"
+
+// dateLayouts are the formats AIS renders dates in, depending on the dateFormat of the request and the user profile.
+var dateLayouts = []string{
+	"20060102",
+	"2006-01-02",
+	"01/02/2006",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Row is a row of a rowset, keyed by column id like "F0092_USER".
+type Row map[string]json.RawMessage
+
+// nextLink returns the link to the next page, if there is one.
+func nextLink(links []Link) string {
+	for _, link := range links {
+		if link.Rel == relNext {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// Map returns the values of the row with blank padding trimmed from strings and numbers as int64 or float64.
+// Dates are returned as AIS renders them, see ParseDate.
+func (r Row) Map() map[string]any {
+	m := make(map[string]any, len(r))
+	for column, raw := range r {
+		m[column] = decodeAny(raw)
+	}
+	return m
+}
+
+func decodeAny(raw json.RawMessage) any {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return string(raw)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// DecodeRows decodes the rows of table into structs. Fields are matched with a jde tag naming the column,
+// either with or without the table, like `jde:"USER"` or `jde:"F0092.USER"`.
+// Strings are trimmed, numbers may be rendered as JSON numbers or strings, and time.Time fields accept the
+// AIS date formats and JDE julian dates. Columns missing from a row leave the field at its zero value.
+func DecodeRows[T any](table string, rows []Row) ([]T, error) {
+	ret := make([]T, 0, len(rows))
+	for i, row := range rows {
+		var v T
+		if err := DecodeRow(table, row, &v); err != nil {
+			return nil, fmt.Errorf("error decoding row %d of %s: %w", i, table, err)
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+// DecodeRow decodes a row of table into the struct dst points to, see DecodeRows.
+func DecodeRow(table string, row Row, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("can't decode a row into %T, a pointer to a struct is needed", dst)
+	}
+	rv = rv.Elem()
+
+	for _, f := range structFields(rv.Type()) {
+		raw, ok := row[columnKey(table, f.column)]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(raw, rv.Field(f.index)); err != nil {
+			return fmt.Errorf("column %s: %w", f.column, err)
+		}
+	}
+
+	return nil
+}
+
+// columnKey turns a column as written in a tag into the key of the rowset, like F0092_USER.
+func columnKey(table string, column string) string {
+	if strings.Contains(column, ".") {
+		return strings.Replace(column, ".", "_", 1)
+	}
+	return table + "_" + column
+}
+
+type taggedField struct {
+	index  int
+	column string
+}
+
+var fieldCache sync.Map
+
+func structFields(t reflect.Type) []taggedField {
+	if v, ok := fieldCache.Load(t); ok {
+		fields, _ := v.([]taggedField)
+		return fields
+	}
+
+	var fields []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		column, ok := f.Tag.Lookup("jde")
+		if !ok || column == "-" || !f.IsExported() {
+			continue
+		}
+		fields = append(fields, taggedField{index: i, column: column})
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func decodeValue(raw json.RawMessage, v reflect.Value) error {
+	text, err := rawText(raw)
+	if err != nil {
+		return err
+	}
+
+	if v.Type() == timeType {
+		t, err := ParseDate(text)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if text == "" {
+			return nil
+		}
+		i, err := parseInt(text)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("%s overflows %s", text, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		if text == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		v.SetBool(parseFlag(text))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// rawText returns a JSON string or number as trimmed text. null is returned as an empty string.
+func rawText(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(s), nil
+	}
+
+	return string(raw), nil
+}
+
+// parseInt accepts integers rendered with decimals, like "4242.00".
+func parseInt(text string) (int64, error) {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int64(f)) {
+		return 0, fmt.Errorf("%s is not an integer", text)
+	}
+	return int64(f), nil
+}
+
+// parseFlag reads the Y/N and 1/0 flags JDE stores in single character columns.
+func parseFlag(text string) bool {
+	switch strings.ToUpper(text) {
+	case "Y", "1", "TRUE":
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseDate parses a date as AIS renders it, or a JDE julian date (CYYDDD). A blank date is the zero time.
+func ParseDate(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || text == "0" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+
+	if t, ok := parseJulian(text); ok {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", text)
+}
+
+// parseJulian parses the CYYDDD dates JDE stores: C is the century after 1900, YY the year and DDD the day of the year.
+func parseJulian(text string) (time.Time, bool) {
+	if len(text) < 4 || len(text) > 6 {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.Atoi(text)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+
+	year := 1900 + n/1000
+	day := n % 1000
+	if day < 1 || day > 366 {
+		return time.Time{}, false
+	}
+
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day-1), true
+}
@@ -0,0 +1,105 @@
+package jde
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeRows(t *testing.T) {
+	body := `{
+		"fs_DATABROWSE_F0101": {
+			"title": "Address Book",
+			"data": {"gridData": {
+				"columns": {"F0101_AN8": "Address Number", "F0101_ALPH": "Alpha Name"},
+				"rowset": [
+					{"F0101_AN8": 4242, "F0101_ALPH": "Doe, Jane    ", "F0101_UPMJ": "20240115", "F0101_TAXC": "Y"},
+					{"F0101_AN8": "  17.00", "F0101_ALPH": "", "F0101_UPMJ": "124046", "F0101_TAXC": "N"}
+				],
+				"summary": {"records": 2, "moreRecords": false}
+			}}
+		},
+		"links": [{"rel": "self", "href": "https://ais/self"}, {"rel": "next", "href": "https://ais/next"}]
+	}`
+
+	var table string
+	var rowset []Row
+	page, err := decodeStream(strings.NewReader(body), func(tbl string, row Row) error {
+		table = tbl
+		rowset = append(rowset, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table != "F0101" || page.nextUrl != "https://ais/next" {
+		t.Fatalf("unexpected table %q or next link %q", table, page.nextUrl)
+	}
+
+	type addressBook struct {
+		AddressNumber int64     `jde:"AN8"`
+		Name          string    `jde:"F0101.ALPH"`
+		Updated       time.Time `jde:"UPMJ"`
+		Taxable       bool      `jde:"TAXC"`
+		Missing       string    `jde:"MISSING"`
+	}
+
+	rows, err := DecodeRows[addressBook](table, rowset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []addressBook{
+		{AddressNumber: 4242, Name: "Doe, Jane", Updated: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), Taxable: true},
+		{AddressNumber: 17, Updated: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	m := rowset[0].Map()
+	if m["F0101_AN8"] != int64(4242) || m["F0101_ALPH"] != "Doe, Jane" {
+		t.Errorf("unexpected map %v", m)
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	body := `{
+		"ServiceRequest1": {"unrelated": [{"nested": [1, 2]}]},
+		"fs_DATABROWSE_F0092": {
+			"title": "User Profiles",
+			"data": {"gridData": {
+				"id": 52,
+				"columns": {"F0092_USER": "User ID"},
+				"rowset": [{"F0092_USER": "JDE  "}, {"F0092_USER": "DEMO"}],
+				"summary": {"records": 2, "moreRecords": true}
+			}}
+		},
+		"links": [{"rel": "next", "href": "https://ais/next"}]
+	}`
+
+	var users []string
+	page, err := decodeStream(strings.NewReader(body), func(table string, row Row) error {
+		var u User
+		if err := DecodeRow(table, row, &u); err != nil {
+			return err
+		}
+		users = append(users, u.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(users, ",") != "JDE,DEMO" {
+		t.Errorf("got users %v", users)
+	}
+	if page.table != "F0092" || page.rows != 2 || !page.moreRecords || page.nextUrl != "https://ais/next" {
+		t.Errorf("unexpected page %+v", page)
+	}
+}
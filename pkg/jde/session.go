@@ -0,0 +1,287 @@
+package jde
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+// statusInvalidToken is the non-standard status AIS answers with once a token has expired or was logged out.
+const statusInvalidToken = 444
+
+// errLoggedOut is returned by requests that need an AIS session once the client logged out.
+var errLoggedOut = errors.New("AIS session was logged out")
+
+// cursor describes how a nextUrl was reached: the request that opened it and how many rows were already returned.
+type cursor struct {
+	request   DataRequestBody
+	delivered int
+}
+
+// rowsPage describes a page of rows of table once they were read: how many rows it held and the url of the next page.
+type rowsPage struct {
+	table   string
+	rows    int
+	nextUrl string
+	// moreRecords tells whether AIS left rows out of the page.
+	moreRecords bool
+}
+
+// session returns the token of the current AIS session, opening a session if there is none yet.
+// Without token authentication there is no session and the token is empty.
+func (c *Client) session(ctx context.Context) (string, error) {
+	if !c.creds.AuthMode.usesSession() {
+		return "", nil
+	}
+
+	c.mtx.RLock()
+	token := c.token
+	c.mtx.RUnlock()
+	if token != "" {
+		return token, nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// another request may have opened the session while we were waiting for the lock.
+	if c.token != "" {
+		return c.token, nil
+	}
+	if c.loggedOut {
+		return "", errLoggedOut
+	}
+
+	token, err := c.Authenticate(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error authenticating: %w", err)
+	}
+	c.token = token
+
+	return token, nil
+}
+
+// reauthenticate opens a new AIS session, unless another request already replaced the expired token.
+func (c *Client) reauthenticate(ctx context.Context, expired string) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.token != expired && c.token != "" {
+		return c.token, nil
+	}
+	if c.loggedOut {
+		return "", errLoggedOut
+	}
+
+	ctxzap.Extract(ctx).Info("baton-jd-edwards: AIS session expired, requesting a new token")
+
+	token, err := c.Authenticate(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error re-authenticating expired AIS session: %w", err)
+	}
+	c.token = token
+
+	return token, nil
+}
+
+// Logout releases the AIS session, if one was opened. Cursors of the session can't be followed afterwards, and
+// requests that need a session fail from then on instead of opening another one.
+func (c *Client) Logout(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.loggedOut = true
+	if c.token == "" {
+		return nil
+	}
+
+	token := c.token
+	c.token = ""
+	c.cursors.Range(func(key, _ any) bool {
+		c.cursors.Delete(key)
+		return true
+	})
+	c.links.Range(func(key, _ any) bool {
+		c.links.Delete(key)
+		return true
+	})
+
+	url, _ := url.JoinPath(c.baseUrl, tokenrequest, logout)
+	_, err := c.send(ctx, token, http.MethodPost, url, LogoutBody{Token: token}, nil)
+	if err != nil && !logoutUnsupported(err) {
+		return fmt.Errorf("error logging out of AIS session: %w", err)
+	}
+
+	return nil
+}
+
+// logoutUnsupported reports whether the AIS server has no logout endpoint, the session is then left to expire.
+func logoutUnsupported(err error) bool {
+	var aisErr *AISError
+	return errors.As(err, &aisErr) && (aisErr.StatusCode == http.StatusNotFound || aisErr.StatusCode == http.StatusNotImplemented)
+}
+
+// sessionExpired reports whether AIS rejected the request because the session token is no longer valid.
+func sessionExpired(err error) bool {
+	var aisErr *AISError
+	return errors.As(err, &aisErr) && aisErr.Kind == ErrorSessionExpired
+}
+
+// listRows runs the data request and hands the rows of the first page to fn.
+func (c *Client) listRows(ctx context.Context, dataRequest DataRequestBody, fn rowFunc) (rowsPage, error) {
+	url, _ := url.JoinPath(c.baseUrl, dataservice)
+	dataRequest.SessionFields = c.sessionFields()
+	page, err := c.streamRequest(ctx, url, dataRequest, fn)
+	if err != nil {
+		return rowsPage{}, err
+	}
+
+	if page.table == "" {
+		page.table = dataRequest.TargetName
+	}
+
+	if !c.features.NextPage {
+		page.nextUrl = ""
+		return page, nil
+	}
+
+	return c.page(dataRequest, 0, page), nil
+}
+
+// streamRequest streams the request within the AIS session, see inSession.
+func (c *Client) streamRequest(ctx context.Context, reqUrl string, payload interface{}, fn rowFunc) (rowsPage, error) {
+	var page rowsPage
+	err := c.inSession(ctx, func(token string) error {
+		var err error
+		page, err = c.stream(ctx, token, reqUrl, payload, fn)
+		return err
+	})
+	if err != nil {
+		return rowsPage{}, err
+	}
+
+	return page, nil
+}
+
+// inSession calls send with the token of the AIS session. If the session expired, it re-authenticates and calls
+// send once more; AIS rejects expired tokens before any row is read.
+func (c *Client) inSession(ctx context.Context, send func(token string) error) error {
+	token, err := c.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = send(token)
+	if err == nil || !c.creds.AuthMode.usesSession() || !sessionExpired(err) {
+		return err
+	}
+
+	token, err = c.reauthenticate(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return send(token)
+}
+
+// fetchMoreRows follows nextUrl and hands its rows to fn. If the session it belonged to expired, the page is rebuilt
+// in a new session.
+func (c *Client) fetchMoreRows(ctx context.Context, nextUrl string, fn rowFunc) (rowsPage, error) {
+	var cur cursor
+	v, known := c.cursors.Load(nextUrl)
+	if known {
+		cur, _ = v.(cursor)
+	}
+
+	var page rowsPage
+	sent := false
+	err := c.inSession(ctx, func(token string) error {
+		var err error
+		if sent {
+			// inSession only sends again once the session expired, the cursor died with it and the page is rebuilt
+			// from the request behind it.
+			if !known {
+				return fmt.Errorf("AIS session expired and the pagination cursor %s can't be recovered", nextUrl)
+			}
+			page, err = c.resume(ctx, cur, fn)
+			return err
+		}
+
+		sent = true
+		page, err = c.stream(ctx, token, nextUrl, nil, fn)
+		if err != nil {
+			return err
+		}
+		page = c.page(cur.request, cur.delivered, page)
+		return nil
+	})
+	if err != nil {
+		// the cursor is kept for the page to be requested again.
+		return rowsPage{}, err
+	}
+	c.cursors.Delete(nextUrl)
+
+	return page, nil
+}
+
+// resume replays the request behind a dead cursor and skips the rows that were already returned.
+func (c *Client) resume(ctx context.Context, cur cursor, fn rowFunc) (rowsPage, error) {
+	ctxzap.Extract(ctx).Info(
+		"baton-jd-edwards: recovering pagination cursor after re-authentication",
+	)
+
+	skip := cur.delivered
+	emitted := 0
+	skipping := func(table string, row Row) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		emitted++
+		return fn(table, row)
+	}
+
+	page, err := c.listRows(ctx, cur.request, skipping)
+	if err != nil {
+		return rowsPage{}, err
+	}
+
+	// pages that only held rows returned before are skipped entirely.
+	seen := page.rows
+	for emitted == 0 && page.nextUrl != "" {
+		c.cursors.Delete(page.nextUrl)
+
+		page, err = c.streamRequest(ctx, page.nextUrl, nil, skipping)
+		if err != nil {
+			return rowsPage{}, err
+		}
+		page = c.page(cur.request, seen, page)
+		seen += page.rows
+	}
+
+	page.rows = emitted
+	return page, nil
+}
+
+// page remembers the cursor of the next page of the rows that were read.
+func (c *Client) page(request DataRequestBody, delivered int, page rowsPage) rowsPage {
+	if page.table == "" {
+		page.table = request.TargetName
+	}
+
+	if !page.moreRecords || page.nextUrl == "" {
+		page.nextUrl = ""
+		return page
+	}
+
+	c.cursors.Store(page.nextUrl, cursor{
+		request:   request,
+		delivered: delivered + page.rows,
+	})
+
+	return page
+}
@@ -0,0 +1,151 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestFetchMoreRowsAfterExpiry expires the session in the middle of the pagination of F0092 and checks that every
+// row is returned once, and that the cursor of a page survives a failed request.
+func TestFetchMoreRowsAfterExpiry(t *testing.T) {
+	users := []string{"A", "B", "C", "D", "E"}
+
+	var mtx sync.Mutex
+	tokens := 0
+	current := ""
+	failNext := true
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasSuffix(r.URL.Path, tokenrequest) {
+			tokens++
+			current = fmt.Sprintf("t%d", tokens)
+			fmt.Fprintf(w, `{"userInfo": {"token": %q}}`, current)
+			return
+		}
+		if r.Header.Get("jde-AIS-Auth") != current {
+			w.WriteHeader(statusInvalidToken)
+			return
+		}
+
+		offset := 0
+		if _, after, ok := strings.Cut(r.URL.Path, "/next/"); ok {
+			if failNext {
+				failNext = false
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message": "bad request"}`)
+				return
+			}
+			offset, _ = strconv.Atoi(after)
+		} else {
+			var req DataRequestBody
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("error decoding request: %v", err)
+			}
+		}
+
+		end := min(offset+2, len(users))
+		var rows []string
+		for _, u := range users[offset:end] {
+			rows = append(rows, fmt.Sprintf(`{"F0092_USER": %q}`, u))
+		}
+		links := ""
+		if end < len(users) {
+			links = fmt.Sprintf(`, "links": [{"rel": "next", "href": "%s/jderest/v2/dataservice/next/%d"}]`, srv.URL, end)
+		}
+		fmt.Fprintf(w, `{"fs_DATABROWSE_F0092": {"data": {"gridData": {"rowset": [%s], "summary": {"records": %d, "moreRecords": %t}}}}%s}`,
+			strings.Join(rows, ","), len(rows), end < len(users), links)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.Client(), srv.URL, Credentials{AuthMode: AuthModeToken, Username: "u", Password: "p"}, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var got []string
+	collect := func(table string, row Row) error {
+		var u User
+		if err := DecodeRow(table, row, &u); err != nil {
+			return err
+		}
+		got = append(got, u.ID)
+		return nil
+	}
+
+	page, err := c.listRows(ctx, c.Users().request, collect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.fetchMoreRows(ctx, page.nextUrl, collect); err == nil {
+		t.Fatal("expected the next page to fail")
+	}
+	if _, ok := c.cursors.Load(page.nextUrl); !ok {
+		t.Fatal("expected the cursor to survive the failed request")
+	}
+
+	// the session expires, the next page can only be reached by replaying the request in a new session.
+	mtx.Lock()
+	current = "expired"
+	mtx.Unlock()
+
+	for page.nextUrl != "" {
+		page, err = c.fetchMoreRows(ctx, page.nextUrl, collect)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if strings.Join(got, ",") != strings.Join(users, ",") {
+		t.Errorf("got users %v, want %v", got, users)
+	}
+	if tokens != 2 {
+		t.Errorf("expected a single re-authentication, got %d tokens", tokens)
+	}
+}
+
+func TestLogoutWithoutEndpoint(t *testing.T) {
+	for _, code := range []int{http.StatusNotFound, http.StatusNotImplemented} {
+		logouts := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, logout) {
+				logouts++
+				w.WriteHeader(code)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"userInfo": {"token": "t1"}}`)
+		}))
+
+		c, err := NewClient(srv.Client(), srv.URL, Credentials{AuthMode: AuthModeToken, Username: "u", Password: "p"}, ClientOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.UseFeatures(NewFeatures("v2", ConfigResponse{}))
+
+		ctx := context.Background()
+		if _, err := c.session(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Logout(ctx); err != nil {
+			t.Errorf("status %d: expected logout to succeed, got %v", code, err)
+		}
+		if logouts == 0 {
+			t.Errorf("status %d: expected a logout request", code)
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,229 @@
+package jde
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// errStopRows is returned by a rowFunc to stop reading the rowset. The request is then considered successful.
+var errStopRows = errors.New("stop reading rows")
+
+// rowFunc is called for every row of a rowset, in order, as the response is read.
+type rowFunc func(table string, row Row) error
+
+// interruptedError wraps an error that occurred after rows were handed out, so the request isn't retried.
+type interruptedError struct {
+	err error
+}
+
+func (e *interruptedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *interruptedError) Unwrap() error {
+	return e.err
+}
+
+// stream sends a dataservice request and walks the rowset of the response row by row, without buffering the
+// response, see decodeStream. Unlike do, only the rows being decoded are held in memory.
+func (c *Client) stream(ctx context.Context, token string, reqUrl string, payload interface{}, fn rowFunc) (rowsPage, error) {
+	var page rowsPage
+	err := c.streamBody(ctx, token, reqUrl, payload, func(body io.Reader, emitted func()) error {
+		var err error
+		page, err = decodeStream(body, func(table string, row Row) error {
+			emitted()
+			return fn(table, row)
+		})
+		return err
+	})
+
+	return page, err
+}
+
+// streamBody sends a dataservice request and hands the response body to read, which calls emitted before it hands
+// out a row. Once a row was handed out the request isn't retried. read may stop early with errStopRows.
+func (c *Client) streamBody(ctx context.Context, token string, reqUrl string, payload interface{}, read func(body io.Reader, emitted func()) error) error {
+	u, err := url.Parse(reqUrl)
+	if err != nil {
+		return err
+	}
+
+	headers, err := c.authHeaders(token)
+	if err != nil {
+		return err
+	}
+
+	err = c.withRetry(ctx, idempotent(payload), func() error {
+		release, err := c.limiter.wait(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		req, err := c.newRequest(ctx, RequestParams{
+			Url:     u.String(),
+			Method:  http.MethodPost,
+			Payload: payload,
+			Headers: headers,
+		})
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.httpClient.HttpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return newAISError(resp, false)
+		}
+
+		emitted := false
+		err = read(resp.Body, func() { emitted = true })
+		if err != nil && emitted && !errors.Is(err, errStopRows) {
+			return &interruptedError{err: err}
+		}
+		return err
+	})
+	if errors.Is(err, errStopRows) {
+		return nil
+	}
+
+	return err
+}
+
+// decodeStream reads a dataservice response token by token. Rows of the rowset are handed to fn one at a time,
+// the links and the summary are returned with the number of rows read.
+func decodeStream(r io.Reader, fn rowFunc) (rowsPage, error) {
+	dec := json.NewDecoder(r)
+	var page rowsPage
+
+	err := walkObject(dec, func(key string) error {
+		switch {
+		case key == "links":
+			var links []Link
+			if err := dec.Decode(&links); err != nil {
+				return fmt.Errorf("error decoding links: %w", err)
+			}
+			page.nextUrl = nextLink(links)
+			return nil
+		case strings.HasPrefix(key, dataBrowsePrefix):
+			page.table = strings.TrimPrefix(key, dataBrowsePrefix)
+			return decodeResource(dec, &page, fn)
+		default:
+			return skipValue(dec)
+		}
+	})
+
+	return page, err
+}
+
+// decodeResource walks {"data": {"gridData": {"rowset": [...], "summary": {...}}}}.
+func decodeResource(dec *json.Decoder, page *rowsPage, fn rowFunc) error {
+	return walkObject(dec, func(key string) error {
+		if key != "data" {
+			return skipValue(dec)
+		}
+		return walkObject(dec, func(key string) error {
+			if key != "gridData" {
+				return skipValue(dec)
+			}
+			return walkObject(dec, func(key string) error {
+				switch key {
+				case "rowset":
+					return decodeRowset(dec, page, fn)
+				case "summary":
+					var summary Summary
+					if err := dec.Decode(&summary); err != nil {
+						return fmt.Errorf("error decoding summary: %w", err)
+					}
+					page.moreRecords = summary.MoreRecords
+					return nil
+				default:
+					return skipValue(dec)
+				}
+			})
+		})
+	})
+}
+
+func decodeRowset(dec *json.Decoder, page *rowsPage, fn rowFunc) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		var row Row
+		if err := dec.Decode(&row); err != nil {
+			return fmt.Errorf("error decoding row %d: %w", page.rows, err)
+		}
+		page.rows++
+		if err := fn(page.table, row); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+// walkObject calls fn with every key of the object that starts at the next token. fn must consume the value.
+func walkObject(dec *json.Decoder, fn func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("unexpected token %v, expected an object key", tok)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("unexpected token %v, expected %v", tok, delim)
+	}
+	return nil
+}
+
+// skipValue discards the next value, reading through nested objects and arrays token by token.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package jde
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configures how the connection to the AIS server is secured.
+type TLSOptions struct {
+	// CABundle is the path of a PEM file with the certificate authorities trusted in addition to the system ones.
+	CABundle string
+	// ClientCert and ClientKey are the paths of the PEM encoded certificate and key presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// MinVersion is the lowest TLS version accepted, "1.2" or "1.3".
+	MinVersion string
+}
+
+// NewTLSConfig builds the TLS configuration used for every request to the AIS server.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: minVersion,
+	}
+
+	if opts.CABundle != "" {
+		bundle, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CABundle)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported minimum TLS version %q, expected 1.2 or 1.3", version)
+	}
+}
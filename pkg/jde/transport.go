@@ -0,0 +1,133 @@
+package jde
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/sdk"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// maxIdleConnsPerHost is raised from the net/http default of 2, since every request goes to the same AIS server.
+const maxIdleConnsPerHost = 16
+
+// HTTPOptions configures the HTTP client shared by every request to the AIS server.
+type HTTPOptions struct {
+	TLS TLSOptions
+	// ProxyURL is the proxy AIS requests go through. When empty, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honoured.
+	ProxyURL string
+	// NoProxy lists the hosts that are reached without the proxy, in the NO_PROXY format.
+	NoProxy string
+	// RequestTimeout bounds every request, from dialing until the response has been read. Zero means no timeout.
+	RequestTimeout time.Duration
+	// UserAgent identifies the connector, the baton-sdk version is appended to it.
+	UserAgent string
+}
+
+// NewHTTPClient returns the client used for all AIS traffic: the config probe, token requests, OAuth token
+// requests and dataservice calls share its connections, proxy and TLS settings.
+func NewHTTPClient(ctx context.Context, opts HTTPOptions) (*http.Client, error) {
+	tlsConfig, err := NewTLSConfig(opts.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring TLS: %w", err)
+	}
+
+	proxy, err := proxyFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// based on http.DefaultTransport
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	userAgent := opts.UserAgent + " baton-sdk/" + sdk.Version
+	if opts.UserAgent == "" {
+		userAgent = "baton-sdk/" + sdk.Version
+	}
+
+	return &http.Client{
+		Timeout: opts.RequestTimeout,
+		Transport: &loggingTransport{
+			next:      transport,
+			userAgent: userAgent,
+		},
+	}, nil
+}
+
+func proxyFunc(opts HTTPOptions) (func(*http.Request) (*url.URL, error), error) {
+	if opts.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	if _, err := url.Parse(opts.ProxyURL); err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	noProxy := opts.NoProxy
+	if noProxy == "" {
+		noProxy = httpproxy.FromEnvironment().NoProxy
+	}
+
+	cfg := httpproxy.Config{
+		HTTPProxy:  opts.ProxyURL,
+		HTTPSProxy: opts.ProxyURL,
+		NoProxy:    noProxy,
+	}
+	proxy := cfg.ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// loggingTransport sets the User-Agent and logs every request at debug level, like the uhttp transport does.
+type loggingTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request of the caller.
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	l := ctxzap.Extract(req.Context())
+	fields := []zap.Field{
+		zap.String("http.method", req.Method),
+		zap.String("http.url_details.host", req.URL.Host),
+		zap.String("http.url_details.path", req.URL.Path),
+	}
+	l.Debug("Request started", fields...)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	if resp != nil {
+		fields = append(fields, zap.Int("http.status_code", resp.StatusCode))
+	}
+	l.Debug("Request complete", fields...)
+
+	return resp, err
+}
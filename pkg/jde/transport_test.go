@@ -0,0 +1,76 @@
+package jde
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyFunc(t *testing.T) {
+	t.Setenv("NO_PROXY", "env.example.com")
+
+	tests := []struct {
+		name    string
+		opts    HTTPOptions
+		host    string
+		proxied bool
+	}{
+		{"proxied", HTTPOptions{ProxyURL: "http://proxy.example.com:3128"}, "ais.example.com", true},
+		{"no proxy option", HTTPOptions{ProxyURL: "http://proxy.example.com:3128", NoProxy: "ais.example.com"}, "ais.example.com", false},
+		{"no proxy domain", HTTPOptions{ProxyURL: "http://proxy.example.com:3128", NoProxy: ".example.com"}, "ais.example.com", false},
+		{"NO_PROXY from the environment", HTTPOptions{ProxyURL: "http://proxy.example.com:3128"}, "env.example.com", false},
+		{"no proxy option overrides NO_PROXY", HTTPOptions{ProxyURL: "http://proxy.example.com:3128", NoProxy: "ais.example.com"}, "env.example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := proxyFunc(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "https://"+tt.host+"/jderest/v2/dataservice", nil)
+			u, err := proxy(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.proxied && (u == nil || u.Host != "proxy.example.com:3128"):
+				t.Errorf("expected %s to go through the proxy, got %v", tt.host, u)
+			case !tt.proxied && u != nil:
+				t.Errorf("expected %s not to go through the proxy, got %v", tt.host, u)
+			}
+		})
+	}
+
+	if _, err := proxyFunc(HTTPOptions{ProxyURL: "http://proxy.example.com:%zz"}); err == nil {
+		t.Error("expected an invalid proxy URL to be refused")
+	}
+}
+
+func TestLoggingTransportUserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	transport := &loggingTransport{next: http.DefaultTransport, userAgent: "baton-jd-edwards"}
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got != "baton-jd-edwards" {
+		t.Errorf("got User-Agent %q", got)
+	}
+	if ua := req.Header.Get("User-Agent"); ua != "" {
+		t.Errorf("expected the request of the caller to be left alone, got User-Agent %q", ua)
+	}
+}
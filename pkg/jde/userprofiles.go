@@ -0,0 +1,71 @@
+package jde
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Forms and controls of P0092, the User Profiles application, in its standard ZJDE0001 version. AIS addresses
+// controls by the ids Form Design Aid gives them, they show in the item help of the web client.
+const (
+	userProfilesVersion = "ZJDE0001"
+
+	// W0092D, Work With User Profiles, lists the user profiles in a grid.
+	workWithUserProfilesForm = "P0092_W0092D"
+	workWithUserProfilesOID  = "W0092D"
+	// userIDQBEControl is the query by example cell above the User ID column of the grid, grid 1 column 7.
+	userIDQBEControl = "1[7]"
+	// findButton runs the query of the grid.
+	findButton = "15"
+	// firstGridRow is the first row of grid 1, the only one left once the grid is queried by user ID.
+	firstGridRow = "1.0"
+	// selectButton opens the selected row in W0092A.
+	selectButton = "4"
+
+	// W0092A, User Profile Revisions, edits a single user profile.
+	userProfileRevisionsOID = "W0092A"
+	// userGroupField is the User Class/Group field, F0092.UGRP.
+	userGroupField = "19"
+	// okButton saves the profile and returns to W0092D.
+	okButton = "11"
+)
+
+// SetUserGroup moves the user to the user group through P0092, so that the application validates the change.
+// An empty group removes the user from its group.
+func (c *Client) SetUserGroup(ctx context.Context, userID string, groupID string) (err error) {
+	stack, err := c.OpenAppStack(ctx, FormRequest{
+		FormName: workWithUserProfilesForm,
+		Version:  userProfilesVersion,
+		FormActions: []FormAction{
+			{Command: CommandSetQBEValue, ControlID: userIDQBEControl, Value: userID},
+			{Command: CommandDoAction, ControlID: findButton},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := stack.Close(ctx); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("error closing %s: %w", workWithUserProfilesForm, closeErr))
+		}
+	}()
+
+	err = stack.Execute(ctx, workWithUserProfilesOID,
+		FormAction{Command: CommandSelectRow, ControlID: firstGridRow},
+		FormAction{Command: CommandDoAction, ControlID: selectButton},
+	)
+	if err != nil {
+		return fmt.Errorf("error selecting user %s: %w", userID, err)
+	}
+
+	err = stack.Execute(ctx, userProfileRevisionsOID,
+		FormAction{Command: CommandSetControlValue, ControlID: userGroupField, Value: groupID},
+		FormAction{Command: CommandDoAction, ControlID: okButton},
+	)
+	if err != nil {
+		return fmt.Errorf("error setting user group of %s: %w", userID, err)
+	}
+
+	return nil
+}